@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ManuelJNunez/news_service/internal/config"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// errUsage is returned by the commands called with wrong arguments, the usage is printed
+var errUsage = errors.New("invalid arguments")
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+// Subcommands of the api binary, the config flags go after their arguments, e.g.
+// "api user create alice editor -config config.yaml"
+var commands = []command{
+	{
+		name:  "serve",
+		usage: "serve [flags]",
+		help:  "serve the REST API, the default when no command is given",
+		run: func(args []string) error {
+			serve(args)
+			return nil
+		},
+	},
+	{
+		name:  "migrate",
+		usage: "migrate up|down|status [flags]\n  migrate baseline <version> [flags]\n  migrate create <name>",
+		help:  "manage the PostgreSQL schema",
+		run:   runMigrate,
+	},
+	{
+		name:  "seed",
+		usage: "seed [flags]",
+		help:  "apply the migrations and create the reader, editor and admin accounts, the password is read from stdin",
+		run:   runSeed,
+	},
+	{
+		name:  "user",
+		usage: "user create <username> [role...] [flags]\n  user list [flags]\n  user delete <username> [flags]\n  user set-password <username> [flags]\n  user hash-passwords [flags]",
+		help:  "manage the user accounts, passwords are read from stdin; delete and set-password revoke the sessions and refresh tokens of the user, access tokens stay valid until they expire",
+		run:   runUser,
+	},
+	{
+		name:  "news",
+		usage: "news import [-format jsonl|csv] [-dry-run] [-batch-size n] [file] [flags]\n  news export [-format jsonl|csv] [file] [flags]",
+		help:  "import or export articles as JSON Lines or CSV, stdin or stdout are used without a file",
+		run:   runNews,
+	},
+	{
+		name:  "config",
+		usage: "config check [flags]",
+		help:  "validate the configuration",
+		run:   runConfig,
+	},
+}
+
+// Run the command named by the first argument and return the exit code.
+// Arguments starting with a dash are flags of the default serve command.
+func run(args []string) int {
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+
+		err := cmd.run(args)
+		if errors.Is(err, errUsage) {
+			fmt.Fprintf(os.Stderr, "usage: api %s\n", cmd.usage) //nolint:errcheck
+			return 2
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "api %s: %v\n", name, err) //nolint:errcheck
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name) //nolint:errcheck
+	printUsage(os.Stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: api <command> [arguments] [flags]\n\ncommands:") //nolint:errcheck
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.help) //nolint:errcheck
+	}
+	fmt.Fprintln(w, "\nthe flags are the configuration settings, e.g. -config config.yaml or -db-dsn <dsn>") //nolint:errcheck
+}
+
+// Split the arguments of a command from the config flags that follow them. The
+// flags defined in fs are options of the command and can go before its arguments,
+// e.g. "api news import -format csv news.csv -config config.yaml".
+func splitArgs(args []string, fs *flag.FlagSet) ([]string, []string, error) {
+	if fs == nil {
+		fs = flag.NewFlagSet("", flag.ContinueOnError)
+	}
+
+	var positional, options []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" {
+			positional = append(positional, arg)
+			continue
+		}
+
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		option := fs.Lookup(name)
+		if option == nil {
+			if err := fs.Parse(options); err != nil {
+				return nil, nil, err
+			}
+			return positional, args[i:], nil
+		}
+
+		// Non-boolean options take the next argument as value unless it is given with "="
+		options = append(options, arg)
+		if boolFlag, ok := option.Value.(interface{ IsBoolFlag() bool }); !hasValue && (!ok || !boolFlag.IsBoolFlag()) && i+1 < len(args) {
+			i++
+			options = append(options, args[i])
+		}
+	}
+	return positional, nil, fs.Parse(options)
+}
+
+// Load the configuration of a command, logs go to stderr so they do not mix with its output
+func loadConfig(flags []string) (*config.Config, *slog.Logger, error) {
+	cfg, err := config.Load(flags)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	logger, _, err := initLogger(cfg, os.Stderr)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize logger: %w", err)
+	}
+	return cfg, logger, nil
+}
+
+// Connect to PostgreSQL for the duration of fn
+func withDB(cfg *config.Config, logger *slog.Logger, fn func(db *sql.DB) error) error {
+	db, _, err := initDB(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			logger.Error("failed to close database", slog.Any("error", err))
+		}
+	}()
+
+	return fn(db)
+}
+
+// Connect to MongoDB for the duration of fn
+func withMongoDB(cfg *config.Config, logger *slog.Logger, fn func(client *mongo.Client) error) error {
+	client, err := initMongoDB(cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mongodb: %w", err)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDBConnectTimeout)
+		defer cancel()
+		if err := client.Disconnect(ctx); err != nil {
+			logger.Error("failed to disconnect from mongodb", slog.Any("error", err))
+		}
+	}()
+
+	return fn(client)
+}
+
+// Read a password from the first line of stdin, so it does not show up in the process list
+func readPassword(stdin io.Reader) (string, error) {
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("missing password on stdin")
+	}
+	return password, nil
+}
+
+// Run "api config check", the configuration is validated by config.Load
+func runConfig(args []string) error {
+	positional, flags, err := splitArgs(args, nil)
+	if err != nil || len(positional) != 1 || positional[0] != "check" {
+		return errUsage
+	}
+
+	if _, err := config.Load(flags); err != nil {
+		return err
+	}
+	fmt.Println("configuration is valid")
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitArgs(t *testing.T) {
+	positional, flags, err := splitArgs([]string{"create", "alice", "editor", "-config", "config.yaml"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"create", "alice", "editor"}, positional)
+	assert.Equal(t, []string{"-config", "config.yaml"}, flags)
+
+	positional, flags, err = splitArgs([]string{"import", "-"}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"import", "-"}, positional)
+	assert.Nil(t, flags)
+}
+
+func TestSplitArgsWithOptions(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "", "")
+	dryRun := fs.Bool("dry-run", false, "")
+
+	positional, flags, err := splitArgs([]string{"import", "-format", "csv", "-dry-run", "news.csv", "-db-dsn=dsn"}, fs)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"import", "news.csv"}, positional)
+	assert.Equal(t, []string{"-db-dsn=dsn"}, flags)
+	assert.Equal(t, "csv", *format)
+	assert.True(t, *dryRun)
+
+	_, _, err = splitArgs([]string{"import", "-format"}, fs)
+	assert.Error(t, err)
+}
+
+func TestReadPassword(t *testing.T) {
+	password, err := readPassword(strings.NewReader("s3cret pass\r\nignored\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3cret pass", password)
+
+	password, err = readPassword(strings.NewReader("no newline"))
+	assert.NoError(t, err)
+	assert.Equal(t, "no newline", password)
+
+	_, err = readPassword(strings.NewReader(""))
+	assert.ErrorContains(t, err, "missing password")
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, "csv", formatOf("archive/NEWS.CSV"))
+	assert.Equal(t, "jsonl", formatOf("news.jsonl"))
+	assert.Equal(t, "jsonl", formatOf("-"))
+}
+
+func TestRunRejectsInvalidArguments(t *testing.T) {
+	assert.Equal(t, 2, run([]string{"unknown"}))
+	assert.Equal(t, 2, run([]string{"user", "delete"}))
+	assert.Equal(t, 2, run([]string{"migrate", "sideways"}))
+	assert.Equal(t, 2, run([]string{"news", "import", "-batch-size", "many"}))
+	assert.Equal(t, 2, run([]string{"config"}))
+	assert.Equal(t, 0, run([]string{"help"}))
+}
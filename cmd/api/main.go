@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"io"
 	"log"
 	"log/slog"
 	"net/http"
@@ -12,33 +13,72 @@ import (
 	"time"
 
 	"github.com/ManuelJNunez/news_service/internal/config"
+	"github.com/ManuelJNunez/news_service/internal/database"
 	"github.com/ManuelJNunez/news_service/internal/health"
+	"github.com/ManuelJNunez/news_service/internal/lockout"
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/metrics"
 	"github.com/ManuelJNunez/news_service/internal/news"
+	"github.com/ManuelJNunez/news_service/internal/ratelimit"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/token"
+	"github.com/ManuelJNunez/news_service/internal/tracing"
 	"github.com/ManuelJNunez/news_service/internal/user"
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// Serve the REST API until SIGINT or SIGTERM is received
+func serve(args []string) {
 	// 1) Load configuration
-	cfg, err := config.Load()
+	cfg, err := config.Load(args)
 
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
 
-	// 2) Initialize logger
-	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelDebug,
-	}))
-	slog.SetDefault(logger)
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("failed to print config: %v", err)
+		}
+		return
+	}
+
+	// 2) Initialize logger, its levels can be changed at runtime by admins
+	logger, logLevels, err := initLogger(cfg, os.Stdout)
+	if err != nil {
+		log.Fatalf("failed to initialize logger: %v", err)
+	}
 
 	logger.Info("starting service", slog.String("port", cfg.HTTPPort))
 
+	// Initialize tracing, pending spans are flushed on exit
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Options{
+		Exporter:     cfg.TracingExporter,
+		ServiceName:  cfg.TracingServiceName,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Error("failed to initialize tracing", slog.Any("error", err))
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("failed to flush traces", slog.Any("error", err))
+		}
+	}()
+
 	// 3) Connect to PostgreSQL database
-	db, err := initDB(cfg, logger)
+	db, dbConnector, err := initDB(cfg, logger)
 
 	if err != nil {
 		logger.Error("failed to connect to database", slog.Any("error", err))
@@ -50,6 +90,14 @@ func main() {
 		}
 	}()
 
+	// Apply the pending migrations before the repositories use the schema
+	if cfg.MigrateOnStartup {
+		if err := migrateUp(db); err != nil {
+			logger.Error("failed to apply migrations", slog.Any("error", err))
+			os.Exit(1)
+		}
+	}
+
 	// 4) Connect to MongoDB
 	mongoClient, err := initMongoDB(cfg, logger)
 	if err != nil {
@@ -57,50 +105,119 @@ func main() {
 		os.Exit(1)
 	}
 	defer func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDBConnectTimeout)
 		defer cancel()
 		if err := mongoClient.Disconnect(ctx); err != nil {
 			logger.Error("failed to disconnect from mongodb", slog.Any("error", err))
 		}
 	}()
 
+	// Reconnect when the database credentials are rotated
+	watchCtx, stopWatching := context.WithCancel(context.Background())
+	defer stopWatching()
+	go cfg.WatchSecrets(watchCtx, func(key string, value string) {
+		reloadSecret(cfg, db, dbConnector, logger, key, value)
+	})
+
 	// 5) Build dependencies from news domain
 	newsRepo := news.NewRepository(db)
 	newsSvc := news.NewService(newsRepo)
 	newsHandler := news.NewHandler(newsSvc)
 
+	// Publish the scheduled articles in the background
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if cfg.SchedulerInterval > 0 {
+		go news.NewScheduler(newsSvc, cfg.SchedulerInterval).Run(schedulerCtx)
+	}
+
 	// 6) Build dependencies from user domain
-	usersCollection := mongoClient.Database("app").Collection("users")
-	userRepo := user.NewRepository(usersCollection)
-	userSvc := user.NewService(userRepo)
-	userHandler := user.NewHandler(userSvc)
+	sessionStore, err := initSessionStore(cfg, mongoClient, cfg.MongoDBSessionsCollection)
+	if err != nil {
+		logger.Error("failed to initialize session store", slog.Any("error", err))
+		os.Exit(1)
+	}
+	sessionManager := session.NewManager(sessionStore, cfg.SessionTTL, cfg.SessionCookieSecure)
+
+	refreshStore, err := initSessionStore(cfg, mongoClient, cfg.MongoDBRefreshTokensCollection)
+	if err != nil {
+		logger.Error("failed to initialize refresh token store", slog.Any("error", err))
+		os.Exit(1)
+	}
+	tokenIssuer := token.NewIssuer(cfg.JWTKeys, cfg.JWTActiveKeyID, cfg.JWTAccessTTL, cfg.JWTRefreshTTL, refreshStore)
+
+	userSvc, err := initUserService(cfg, mongoClient, sessionStore, refreshStore)
+	if err != nil {
+		logger.Error("failed to initialize user service", slog.Any("error", err))
+		os.Exit(1)
+	}
+
+	loginAttemptStore, err := initLoginAttemptStore(cfg, mongoClient)
+	if err != nil {
+		logger.Error("failed to initialize login attempt store", slog.Any("error", err))
+		os.Exit(1)
+	}
+	loginGuard := lockout.NewGuard(loginAttemptStore, lockout.Policy{
+		MaxFailuresPerUser: cfg.LoginMaxFailuresPerUser,
+		MaxFailuresPerIP:   cfg.LoginMaxFailuresPerIP,
+		Window:             cfg.LoginFailureWindow,
+		BaseLockout:        cfg.LoginLockoutBase,
+		MaxLockout:         cfg.LoginLockoutMax,
+	})
+	userHandler := user.NewHandler(userSvc, sessionManager, tokenIssuer, loginGuard)
 
 	// 7) Configure Gin (web framework)
-	router := gin.Default()
+	router, err := newRouter(cfg)
+	if err != nil {
+		logger.Error("failed to configure router", slog.Any("error", err))
+		os.Exit(1)
+	}
 	router.LoadHTMLGlob("templates/*.html")
+	router.Use(gin.Recovery(), tracing.Middleware(), logging.Middleware(), metrics.Middleware())
+	router.Use(user.LoadUser(userSvc, sessionManager), user.BearerAuth(tokenIssuer))
 	router_group := router.Group("")
 
-	// Register health route
-	healthHandler := health.NewHandler()
+	// Register health routes, the service is ready when both databases answer
+	healthHandler := health.NewHandler(
+		health.Checker{Name: "postgres", Critical: true, Timeout: 2 * time.Second, Check: db.PingContext},
+		health.Checker{Name: "mongodb", Critical: true, Timeout: 2 * time.Second, Check: func(ctx context.Context) error {
+			return mongoClient.Ping(ctx, nil)
+		}},
+	)
 	health.RegisterRoutes(router_group, healthHandler)
 
-	// Register news routes
-	news.RegisterRoutes(router_group, newsHandler)
+	// Register Prometheus metrics route
+	metrics.RegisterRoutes(router_group)
+
+	// Register news routes, writing articles requires the editor role
+	news_group := router_group.Group("", rateLimit(cfg.RateLimitNews)...)
+	news.RegisterRoutes(news_group, newsHandler, user.RequireAuth(userSvc, loginGuard), user.RequireRole(user.RoleEditor))
 
-	// Register user routes
-	user.RegisterRoutes(router_group, userHandler)
+	// Register user routes with stricter throttling against credential stuffing
+	auth_group := router_group.Group("", rateLimit(cfg.RateLimitAuth)...)
+	user.RegisterRoutes(auth_group, userHandler)
+
+	// Register admin routes, restricted to admins
+	admin_group := router_group.Group("/admin", rateLimit(cfg.RateLimitDefault)...)
+	admin_group.Use(user.RequireAuth(userSvc, loginGuard), user.RequireRole(user.RoleAdmin))
+	user.RegisterAdminRoutes(admin_group, userHandler)
+	news.RegisterAdminRoutes(admin_group, newsHandler)
+	logging.RegisterAdminRoutes(admin_group, logging.NewHandler(logLevels))
 
 	// 8) Configure HTTP server
 	addr := ":" + cfg.HTTPPort
 	srv := &http.Server{
-		Addr:    addr,
-		Handler: router,
+		Addr:         addr,
+		Handler:      router,
+		ReadTimeout:  cfg.HTTPReadTimeout,
+		WriteTimeout: cfg.HTTPWriteTimeout,
+		IdleTimeout:  cfg.HTTPIdleTimeout,
 	}
 
 	// 9) Launch webserver on a separate Thread using a Goroutine
 	go func() {
 		logger.Info("HTTP server listening", slog.String("addr", addr))
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := listen(srv, cfg); err != nil && err != http.ErrServerClosed {
 			logger.Error("server listen error", slog.Any("error", err))
 		}
 	}()
@@ -111,7 +228,7 @@ func main() {
 	<-quit
 	logger.Info("shutting down server")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.HTTPShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
@@ -121,16 +238,60 @@ func main() {
 	logger.Info("server exiting")
 }
 
-func initDB(cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
-	// Open DB driver connection (using the postgres driver)
-	db, err := sql.Open("postgres", cfg.DB_DSN)
+// Create the Gin engine. Client IPs are only taken from X-Forwarded-For when
+// the request comes from a trusted proxy, otherwise clients could pick the IP
+// used to throttle them and to lock out their failed logins.
+func newRouter(cfg *config.Config) (*gin.Engine, error) {
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	return router, nil
+}
 
+// Build the throttling middleware for a route group, none if the limit is disabled
+func rateLimit(limit config.RateLimit) []gin.HandlerFunc {
+	if !limit.Enabled() {
+		return nil
+	}
+	limiter := ratelimit.NewTokenBucket(limit.Requests, limit.Period)
+	return []gin.HandlerFunc{ratelimit.Middleware(limiter, user.RateLimitKey)}
+}
+
+// Serve HTTPS when a certificate is configured, plain HTTP otherwise
+func listen(srv *http.Server, cfg *config.Config) error {
+	if cfg.HTTPTLSCertFile != "" {
+		return srv.ListenAndServeTLS(cfg.HTTPTLSCertFile, cfg.HTTPTLSKeyFile)
+	}
+	return srv.ListenAndServe()
+}
+
+func initLogger(cfg *config.Config, w io.Writer) (*slog.Logger, *logging.Levels, error) {
+	levels := logging.NewLevels(cfg.LogLevel, cfg.LogPackageLevels)
+	handler, err := logging.NewSlogHandler(cfg.LogFormat, w, &slog.HandlerOptions{
+		Level: levels,
+	})
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	// Initialize context with a timeout of 5 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	logger := slog.New(logging.NewLevelHandler(handler, levels))
+	slog.SetDefault(logger)
+	return logger, levels, nil
+}
+
+func initDB(cfg *config.Config, logger *slog.Logger) (*sql.DB, *database.Connector, error) {
+	// Open DB driver connection (using the postgres driver), its DSN can be rotated later
+	connector := database.NewConnector(cfg.DB_DSN)
+	db := sql.OpenDB(connector)
+
+	// Size the connection pool
+	db.SetMaxOpenConns(cfg.DBMaxOpenConns)
+	db.SetMaxIdleConns(cfg.DBMaxIdleConns)
+	db.SetConnMaxLifetime(cfg.DBConnMaxLifetime)
+
+	// Initialize context with the configured connection timeout
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.DBConnectTimeout)
 	defer cancel()
 
 	// Check and open DB connection
@@ -139,20 +300,43 @@ func initDB(cfg *config.Config, logger *slog.Logger) (*sql.DB, error) {
 			logger.Error("failed to close database after ping error", slog.Any("error", closeErr))
 		}
 		logger.Error("database ping failed", slog.Any("error", err))
-		return nil, err
+		return nil, nil, err
+	}
+
+	// Export connection pool statistics
+	if err := metrics.RegisterDB(db, "postgres"); err != nil {
+		logger.Error("failed to register database metrics", slog.Any("error", err))
 	}
 
 	logger.Info("database connection established")
-	return db, nil
+	return db, connector, nil
+}
+
+// Apply a rotated secret, the MongoDB client cannot change its credentials once connected
+func reloadSecret(cfg *config.Config, db *sql.DB, connector *database.Connector, logger *slog.Logger, key string, value string) {
+	switch key {
+	case "DB_DSN":
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.DBConnectTimeout)
+		defer cancel()
+		if err := connector.Rotate(ctx, db, value, cfg.DBMaxIdleConns); err != nil {
+			logger.Error("failed to rotate database credentials", slog.Any("error", err))
+		}
+	default:
+		logger.Warn("secret rotated, restart the service to apply it", slog.String("key", key))
+	}
 }
 
 func initMongoDB(cfg *config.Config, logger *slog.Logger) (*mongo.Client, error) {
-	// Initialize context with a timeout of 10 seconds
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	// Initialize context with the configured connection timeout
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDBConnectTimeout)
 	defer cancel()
 
-	// Connect to MongoDB
-	client, err := mongo.Connect(options.Client().ApplyURI(cfg.MongoDB_URI))
+	// Connect to MongoDB, measuring the latency of every command
+	opts := options.Client().
+		ApplyURI(cfg.MongoDB_URI).
+		SetMaxPoolSize(uint64(cfg.MongoDBMaxPoolSize)).
+		SetMonitor(metrics.MongoMonitor())
+	client, err := mongo.Connect(opts)
 	if err != nil {
 		return nil, err
 	}
@@ -169,3 +353,43 @@ func initMongoDB(cfg *config.Config, logger *slog.Logger) (*mongo.Client, error)
 	logger.Info("mongodb connection successfully established")
 	return client, nil
 }
+
+func initUserService(cfg *config.Config, client *mongo.Client, sessions ...session.Store) (user.Service, error) {
+	usersCollection := client.Database(cfg.MongoDBDatabase).Collection(cfg.MongoDBUsersCollection)
+	userRepo := user.NewRepository(usersCollection)
+	passwordHasher, err := user.NewPasswordHasher(user.PasswordHashOptions{
+		Algorithm:         cfg.PasswordHashAlgorithm,
+		Argon2Memory:      uint32(cfg.Argon2MemoryKiB),
+		Argon2Iterations:  uint32(cfg.Argon2Iterations),
+		Argon2Parallelism: uint8(cfg.Argon2Parallelism),
+		BcryptCost:        cfg.BcryptCost,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user.NewService(userRepo, passwordHasher, sessions...)
+}
+
+func initSessionStore(cfg *config.Config, client *mongo.Client, collection string) (session.Store, error) {
+	if cfg.SessionStore == "memory" {
+		return session.NewMemoryStore(), nil
+	}
+
+	// Creating the TTL index is bounded like the initial connection
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDBConnectTimeout)
+	defer cancel()
+
+	return session.NewMongoStore(ctx, client.Database(cfg.MongoDBDatabase).Collection(collection))
+}
+
+func initLoginAttemptStore(cfg *config.Config, client *mongo.Client) (lockout.Store, error) {
+	if cfg.LoginAttemptStore == "memory" {
+		return lockout.NewMemoryStore(), nil
+	}
+
+	// Creating the TTL index is bounded like the initial connection
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MongoDBConnectTimeout)
+	defer cancel()
+
+	return lockout.NewMongoStore(ctx, client.Database(cfg.MongoDBDatabase).Collection(cfg.MongoDBLoginAttemptsCollection))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ManuelJNunez/news_service/internal/config"
+	"github.com/ManuelJNunez/news_service/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Rate limit key of a request sent from remoteAddr with the given X-Forwarded-For header
+func rateLimitKeyOf(t *testing.T, cfg *config.Config, remoteAddr string, forwardedFor string) string {
+	gin.SetMode(gin.TestMode)
+	router, err := newRouter(cfg)
+	require.NoError(t, err)
+
+	var key string
+	router.GET("/", func(c *gin.Context) {
+		key = ratelimit.KeyByClientIP(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = remoteAddr
+	req.Header.Set("X-Forwarded-For", forwardedFor)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	return key
+}
+
+func TestRouterIgnoresSpoofedForwardedFor(t *testing.T) {
+	cfg := &config.Config{}
+
+	assert.Equal(t, "ip:203.0.113.7", rateLimitKeyOf(t, cfg, "203.0.113.7:5000", "198.51.100.1"))
+	assert.Equal(t, "ip:203.0.113.7", rateLimitKeyOf(t, cfg, "203.0.113.7:5000", "198.51.100.2"))
+}
+
+func TestRouterTrustsConfiguredProxies(t *testing.T) {
+	cfg := &config.Config{TrustedProxies: []string{"10.0.0.0/8"}}
+
+	assert.Equal(t, "ip:198.51.100.1", rateLimitKeyOf(t, cfg, "10.0.0.5:5000", "198.51.100.1"))
+	// Only the proxies are trusted, not the clients sending the header themselves
+	assert.Equal(t, "ip:203.0.113.7", rateLimitKeyOf(t, cfg, "203.0.113.7:5000", "198.51.100.1"))
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/migrate"
+	"github.com/ManuelJNunez/news_service/migrations"
+)
+
+// Directory where new migrations are created, relative to the repository root
+const migrationsDir = "migrations"
+
+// Run "api migrate <action>"
+func runMigrate(args []string) error {
+	positional, flags, err := splitArgs(args, nil)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+
+	// Creating a migration only writes files, no database is needed
+	if positional[0] == "create" {
+		if len(positional) != 2 {
+			return errUsage
+		}
+		up, down, err := migrate.Create(migrationsDir, positional[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created %s\ncreated %s\n", up, down)
+		return nil
+	}
+	// Baselining takes the last version already in the schema
+	var version int64
+	if positional[0] == "baseline" {
+		if len(positional) != 2 {
+			return errUsage
+		}
+		version, err = strconv.ParseInt(positional[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", positional[1])
+		}
+	} else if len(positional) != 1 || !slices.Contains([]string{"up", "down", "status"}, positional[0]) {
+		return errUsage
+	}
+
+	cfg, logger, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+	return withDB(cfg, logger, func(db *sql.DB) error {
+		return migrateAction(context.Background(), db, positional[0], version)
+	})
+}
+
+func migrateAction(ctx context.Context, db *sql.DB, action string, version int64) error {
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch action {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			fmt.Printf("applied %03d_%s\n", m.Version, m.Name)
+		}
+	case "down":
+		m, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rolled back %03d_%s\n", m.Version, m.Name)
+	case "baseline":
+		marked, err := migrator.Baseline(ctx, version)
+		if err != nil {
+			return err
+		}
+		for _, m := range marked {
+			fmt.Printf("marked %03d_%s as applied\n", m.Version, m.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT") //nolint:errcheck
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%03d\t%s\t%s\n", s.Version, s.Name, appliedAt) //nolint:errcheck
+		}
+		return w.Flush()
+	default:
+		return errUsage
+	}
+	return nil
+}
+
+// Apply the pending embedded migrations, used when MIGRATE_ON_STARTUP is set
+func migrateUp(db *sql.DB) error {
+	migrator, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	_, err = migrator.Up(context.Background())
+	return err
+}
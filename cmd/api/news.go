@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ManuelJNunez/news_service/internal/news"
+)
+
+// Run "api news import|export [options] [file]", stdin and stdout are used without a file or with "-"
+func runNews(args []string) error {
+	fs := flag.NewFlagSet("news", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "", "jsonl or csv, taken from the file extension by default")
+	dryRun := fs.Bool("dry-run", false, "validate the import file without writing anything")
+	batchSize := fs.Int("batch-size", news.DefaultImportBatchSize, "articles written in each transaction")
+
+	positional, flags, err := splitArgs(args, fs)
+	if err != nil || len(positional) == 0 || len(positional) > 2 || (positional[0] != "import" && positional[0] != "export") {
+		return errUsage
+	}
+
+	path := "-"
+	if len(positional) == 2 {
+		path = positional[1]
+	}
+	if *format == "" {
+		*format = formatOf(path)
+	}
+
+	cfg, logger, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+	return withDB(cfg, logger, func(db *sql.DB) error {
+		svc := news.NewService(news.NewRepository(db))
+
+		if positional[0] == "import" {
+			return importNews(context.Background(), svc, path, *format, news.ImportOptions{DryRun: *dryRun, BatchSize: *batchSize})
+		}
+		return exportNews(context.Background(), svc, path, *format)
+	})
+}
+
+func importNews(ctx context.Context, svc news.Service, path string, format string, opts news.ImportOptions) error {
+	r, err := openInput(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close() //nolint:errcheck
+
+	reader, err := news.NewRecordReader(format, r)
+	if err != nil {
+		return err
+	}
+
+	report, err := svc.Import(ctx, reader, opts)
+	if report != nil {
+		for _, lineErr := range report.Errors {
+			fmt.Fprintf(os.Stderr, "line %d: %s\n", lineErr.Line, lineErr.Error) //nolint:errcheck
+		}
+		fmt.Fprintf(os.Stderr, "processed %d, valid %d, inserted %d, updated %d, invalid %d\n", //nolint:errcheck
+			report.Processed, report.Valid, report.Inserted, report.Updated, report.Invalid)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Fail dry runs with invalid records, so they can be used to check files in scripts
+	if opts.DryRun && report.Invalid > 0 {
+		return fmt.Errorf("%d invalid records", report.Invalid)
+	}
+	return nil
+}
+
+func exportNews(ctx context.Context, svc news.Service, path string, format string) error {
+	w, err := createOutput(path)
+	if err != nil {
+		return err
+	}
+
+	writer, err := news.NewRecordWriter(format, w)
+	if err != nil {
+		w.Close() //nolint:errcheck
+		return err
+	}
+
+	count, err := svc.Export(ctx, writer)
+	if err != nil {
+		w.Close() //nolint:errcheck
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "exported %d articles\n", count) //nolint:errcheck
+	return w.Close()
+}
+
+// CSV files are recognized by their extension, anything else is JSON Lines
+func formatOf(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return news.FormatCSV
+	}
+	return news.FormatJSONL
+}
+
+func openInput(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+func createOutput(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
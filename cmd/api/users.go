@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ManuelJNunez/news_service/internal/config"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/user"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// Roles that can be given to users, "api seed" creates an account named like each of them
+var userRoles = []string{user.RoleReader, user.RoleEditor, user.RoleAdmin}
+
+// Run "api user <action>"
+func runUser(args []string) error {
+	positional, flags, err := splitArgs(args, nil)
+	if err != nil || len(positional) == 0 {
+		return errUsage
+	}
+
+	action, params := positional[0], positional[1:]
+	switch {
+	case action == "create" && len(params) >= 1:
+	case (action == "list" || action == "hash-passwords") && len(params) == 0:
+	case (action == "delete" || action == "set-password") && len(params) == 1:
+	default:
+		return errUsage
+	}
+	if action == "create" {
+		for _, role := range params[1:] {
+			if !slices.Contains(userRoles, role) {
+				return fmt.Errorf("invalid role %q: must be one of %s", role, strings.Join(userRoles, ", "))
+			}
+		}
+	}
+
+	// Read the password before connecting, so a missing one fails fast
+	var password string
+	if action == "create" || action == "set-password" {
+		var err error
+		if password, err = readPassword(os.Stdin); err != nil {
+			return err
+		}
+	}
+
+	cfg, logger, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+	return withUserService(cfg, logger, func(svc user.Service) error {
+		ctx := context.Background()
+
+		switch action {
+		case "create":
+			created, err := createUser(ctx, svc, params[0], password, params[1:])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("created user %s (%s) with roles %s\n", created.Username, created.ID, strings.Join(created.Roles, ","))
+		case "list":
+			users, err := svc.List(ctx)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tUSERNAME\tROLES") //nolint:errcheck
+			for _, u := range users {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", u.ID, u.Username, strings.Join(u.Roles, ",")) //nolint:errcheck
+			}
+			return w.Flush()
+		case "hash-passwords":
+			hashed, err := svc.HashPlainTextPasswords(ctx)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("hashed %d plain text passwords\n", hashed)
+		case "delete":
+			if err := svc.Delete(ctx, params[0]); err != nil {
+				return err
+			}
+			fmt.Printf("deleted user %s\n", params[0])
+		case "set-password":
+			if err := svc.SetPassword(ctx, params[0], password); err != nil {
+				return err
+			}
+			fmt.Printf("password of %s changed\n", params[0])
+		}
+		return nil
+	})
+}
+
+// Run "api seed", the demo articles are inserted by the migrations and an
+// account is created for every role. Existing accounts are left untouched.
+func runSeed(args []string) error {
+	positional, flags, err := splitArgs(args, nil)
+	if err != nil || len(positional) != 0 {
+		return errUsage
+	}
+
+	password, err := readPassword(os.Stdin)
+	if err != nil {
+		return err
+	}
+
+	cfg, logger, err := loadConfig(flags)
+	if err != nil {
+		return err
+	}
+	if err := withDB(cfg, logger, migrateUp); err != nil {
+		return err
+	}
+
+	return withUserService(cfg, logger, func(svc user.Service) error {
+		for _, role := range userRoles {
+			_, err := createUser(context.Background(), svc, role, password, []string{role})
+			if errors.Is(err, user.ErrUserAlreadyExists) {
+				fmt.Printf("user %s already exists\n", role)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("created user %s\n", role)
+		}
+		return nil
+	})
+}
+
+// Create a user, new users are readers unless other roles are given
+func createUser(ctx context.Context, svc user.Service, username string, password string, roles []string) (*user.UserOutput, error) {
+	created, err := svc.Create(ctx, user.LoginInput{Username: username, Password: password})
+	if err != nil || len(roles) == 0 {
+		return created, err
+	}
+	return svc.SetRoles(ctx, created.ID, roles)
+}
+
+func withUserService(cfg *config.Config, logger *slog.Logger, fn func(svc user.Service) error) error {
+	return withMongoDB(cfg, logger, func(client *mongo.Client) error {
+		// Revoke the sessions and refresh tokens of deleted users and changed passwords
+		var sessions []session.Store
+		for _, collection := range []string{cfg.MongoDBSessionsCollection, cfg.MongoDBRefreshTokensCollection} {
+			store, err := initSessionStore(cfg, client, collection)
+			if err != nil {
+				return fmt.Errorf("failed to initialize session store: %w", err)
+			}
+			sessions = append(sessions, store)
+		}
+
+		svc, err := initUserService(cfg, client, sessions...)
+		if err != nil {
+			return fmt.Errorf("failed to initialize user service: %w", err)
+		}
+		return fn(svc)
+	})
+}
@@ -2,36 +2,486 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	HTTPPort    string
 	DB_DSN      string
 	MongoDB_URI string
+
+	// HTTP server settings, HTTPS is served when a certificate is configured
+	HTTPReadTimeout     time.Duration
+	HTTPWriteTimeout    time.Duration
+	HTTPIdleTimeout     time.Duration
+	HTTPShutdownTimeout time.Duration
+	HTTPTLSCertFile     string
+	HTTPTLSKeyFile      string
+
+	// Client IPs are only taken from X-Forwarded-For when the request comes from these proxies
+	TrustedProxies []string
+
+	// PostgreSQL connection pool settings
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnectTimeout  time.Duration
+
+	// MigrateOnStartup applies the pending migrations before serving requests
+	MigrateOnStartup bool
+
+	// Scheduled articles are published every SchedulerInterval, zero disables it
+	SchedulerInterval time.Duration
+
+	// MongoDB settings
+	MongoDBDatabase                string
+	MongoDBUsersCollection         string
+	MongoDBSessionsCollection      string
+	MongoDBRefreshTokensCollection string
+	MongoDBLoginAttemptsCollection string
+	MongoDBMaxPoolSize             int
+	MongoDBConnectTimeout          time.Duration
+
+	// Log settings, LogPackageLevels overrides LogLevel for some packages
+	LogFormat        string
+	LogLevel         slog.Level
+	LogPackageLevels map[string]slog.Level
+
+	// Session settings
+	SessionStore        string
+	SessionTTL          time.Duration
+	SessionCookieSecure bool
+
+	// JWT settings, token issuance is disabled when no keys are configured
+	JWTKeys        map[string]string
+	JWTActiveKeyID string
+	JWTAccessTTL   time.Duration
+	JWTRefreshTTL  time.Duration
+
+	// Password hashing settings, changing them rehashes passwords on the next login
+	PasswordHashAlgorithm string
+	Argon2MemoryKiB       int
+	Argon2Iterations      int
+	Argon2Parallelism     int
+	BcryptCost            int
+
+	// Failed login tracking settings
+	LoginAttemptStore       string
+	LoginMaxFailuresPerUser int
+	LoginMaxFailuresPerIP   int
+	LoginFailureWindow      time.Duration
+	LoginLockoutBase        time.Duration
+	LoginLockoutMax         time.Duration
+
+	// Request throttling per route group
+	RateLimitAuth    RateLimit
+	RateLimitNews    RateLimit
+	RateLimitDefault RateLimit
+
+	// Tracing settings, spans are only recorded when an exporter is configured
+	TracingExporter     string
+	TracingServiceName  string
+	TracingOTLPEndpoint string
+	TracingOTLPInsecure bool
+	TracingSampleRatio  float64
+
+	// PrintConfig is set by the -print-config flag, the effective configuration
+	// has to be printed instead of starting the service
+	PrintConfig bool
+
+	// Secrets are polled every SecretsReloadInterval by WatchSecrets, zero disables it
+	SecretsReloadInterval time.Duration
+
+	values  []Value
+	secrets []SecretProvider
+}
+
+// RateLimit allows Requests every Period for each client, the zero value disables throttling
+type RateLimit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Enabled reports whether requests must be throttled
+func (r RateLimit) Enabled() bool {
+	return r.Requests > 0
 }
 
-func Load() (*Config, error) {
+// Minimum length of the HMAC secrets used to sign JWTs
+const minJWTSecretLength = 32
+
+// Load reads the configuration from the defaults, the config file, the
+// environment and the command-line flags in args, in increasing precedence,
+// and validates it. Secrets can also be read from <KEY>_FILE files.
+func Load(args []string) (*Config, error) {
+	return LoadWithSecrets(args, EnvSecretProvider{}, FileSecretProvider{})
+}
+
+// LoadWithSecrets works like Load but takes secrets such as DB_DSN from providers
+func LoadWithSecrets(args []string, providers ...SecretProvider) (*Config, error) {
+	src, printConfig, err := newSource(args, os.LookupEnv, providers)
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
-		HTTPPort:    getEnv("HTTP_PORT", "8000"),
-		DB_DSN:      getEnv("DB_DSN", ""),
-		MongoDB_URI: getEnv("MONGODB_URI", ""),
+		HTTPPort:        src.string("HTTP_PORT"),
+		HTTPTLSCertFile: src.string("HTTP_TLS_CERT_FILE"),
+		HTTPTLSKeyFile:  src.string("HTTP_TLS_KEY_FILE"),
+		DB_DSN:          src.string("DB_DSN"),
+		MongoDB_URI:     src.string("MONGODB_URI"),
+
+		MongoDBDatabase:                src.string("MONGODB_DATABASE"),
+		MongoDBUsersCollection:         src.string("MONGODB_USERS_COLLECTION"),
+		MongoDBSessionsCollection:      src.string("MONGODB_SESSIONS_COLLECTION"),
+		MongoDBRefreshTokensCollection: src.string("MONGODB_REFRESH_TOKENS_COLLECTION"),
+		MongoDBLoginAttemptsCollection: src.string("MONGODB_LOGIN_ATTEMPTS_COLLECTION"),
+
+		LogFormat:    src.string("LOG_FORMAT"),
+		SessionStore: src.string("SESSION_STORE"),
+
+		PasswordHashAlgorithm: src.string("PASSWORD_HASH_ALGORITHM"),
+
+		LoginAttemptStore: src.string("LOGIN_ATTEMPT_STORE"),
+
+		TracingExporter:     src.string("TRACING_EXPORTER"),
+		TracingServiceName:  src.string("TRACING_SERVICE_NAME"),
+		TracingOTLPEndpoint: src.string("TRACING_OTLP_ENDPOINT"),
+
+		PrintConfig: printConfig,
+		values:      src.values(),
+		secrets:     providers,
 	}
 
 	if cfg.DB_DSN == "" {
-		return nil, fmt.Errorf("missing DB_DSN environment variable")
+		return nil, fmt.Errorf("missing DB_DSN setting")
 	}
 
 	if cfg.MongoDB_URI == "" {
-		return nil, fmt.Errorf("missing MONGODB_URI environment variable")
+		return nil, fmt.Errorf("missing MONGODB_URI setting")
+	}
+
+	if _, err := strconv.ParseUint(cfg.HTTPPort, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid HTTP_PORT %q: must be a port number", cfg.HTTPPort)
+	}
+
+	if cfg.HTTPReadTimeout, err = src.duration("HTTP_READ_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.HTTPWriteTimeout, err = src.duration("HTTP_WRITE_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.HTTPIdleTimeout, err = src.duration("HTTP_IDLE_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.HTTPShutdownTimeout, err = src.duration("HTTP_SHUTDOWN_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if err := checkTLSFiles(cfg.HTTPTLSCertFile, cfg.HTTPTLSKeyFile); err != nil {
+		return nil, err
+	}
+
+	if cfg.TrustedProxies, err = parseTrustedProxies(src.string("TRUSTED_PROXIES")); err != nil {
+		return nil, err
+	}
+
+	if cfg.DBMaxOpenConns, err = src.int("DB_MAX_OPEN_CONNS", 1, 10000); err != nil {
+		return nil, err
+	}
+
+	if cfg.DBMaxIdleConns, err = src.int("DB_MAX_IDLE_CONNS", 0, 10000); err != nil {
+		return nil, err
+	}
+
+	if cfg.DBMaxIdleConns > cfg.DBMaxOpenConns {
+		return nil, fmt.Errorf("invalid DB_MAX_IDLE_CONNS setting: must not be greater than DB_MAX_OPEN_CONNS")
+	}
+
+	if cfg.DBConnMaxLifetime, err = src.duration("DB_CONN_MAX_LIFETIME"); err != nil {
+		return nil, err
+	}
+
+	if cfg.DBConnectTimeout, err = src.duration("DB_CONNECT_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.MigrateOnStartup, err = src.bool("MIGRATE_ON_STARTUP"); err != nil {
+		return nil, err
+	}
+
+	if cfg.MongoDBDatabase == "" || cfg.MongoDBUsersCollection == "" {
+		return nil, fmt.Errorf("invalid MONGODB_DATABASE or MONGODB_USERS_COLLECTION setting: must not be empty")
+	}
+	if cfg.MongoDBSessionsCollection == "" || cfg.MongoDBRefreshTokensCollection == "" || cfg.MongoDBLoginAttemptsCollection == "" {
+		return nil, fmt.Errorf("invalid MONGODB_SESSIONS_COLLECTION, MONGODB_REFRESH_TOKENS_COLLECTION or MONGODB_LOGIN_ATTEMPTS_COLLECTION setting: must not be empty")
+	}
+
+	if cfg.MongoDBMaxPoolSize, err = src.int("MONGODB_MAX_POOL_SIZE", 1, 10000); err != nil {
+		return nil, err
+	}
+
+	if cfg.MongoDBConnectTimeout, err = src.duration("MONGODB_CONNECT_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		return nil, fmt.Errorf("invalid LOG_FORMAT %q: must be text or json", cfg.LogFormat)
+	}
+
+	if cfg.LogLevel, err = parseLogLevel("LOG_LEVEL", src.string("LOG_LEVEL")); err != nil {
+		return nil, err
+	}
+
+	if cfg.LogPackageLevels, err = parseLogPackageLevels(src.string("LOG_PACKAGE_LEVELS")); err != nil {
+		return nil, err
+	}
+
+	if cfg.SessionStore != "mongo" && cfg.SessionStore != "memory" {
+		return nil, fmt.Errorf("invalid SESSION_STORE %q: must be mongo or memory", cfg.SessionStore)
+	}
+
+	if cfg.SessionTTL, err = src.duration("SESSION_TTL"); err != nil {
+		return nil, err
+	}
+
+	if cfg.SessionCookieSecure, err = src.bool("SESSION_COOKIE_SECURE"); err != nil {
+		return nil, err
+	}
+
+	if cfg.JWTAccessTTL, err = src.duration("JWT_ACCESS_TTL"); err != nil {
+		return nil, err
+	}
+
+	if cfg.JWTRefreshTTL, err = src.duration("JWT_REFRESH_TTL"); err != nil {
+		return nil, err
+	}
+
+	if cfg.JWTKeys, err = parseJWTKeys(src.string("JWT_KEYS")); err != nil {
+		return nil, err
+	}
+
+	if cfg.JWTActiveKeyID, err = activeJWTKeyID(cfg.JWTKeys, src.string("JWT_ACTIVE_KID")); err != nil {
+		return nil, err
+	}
+
+	if cfg.PasswordHashAlgorithm != "argon2id" && cfg.PasswordHashAlgorithm != "bcrypt" {
+		return nil, fmt.Errorf("invalid PASSWORD_HASH_ALGORITHM %q: must be argon2id or bcrypt", cfg.PasswordHashAlgorithm)
+	}
+
+	if cfg.Argon2MemoryKiB, err = src.int("ARGON2_MEMORY_KIB", 8, 4*1024*1024); err != nil {
+		return nil, err
+	}
+
+	if cfg.Argon2Iterations, err = src.int("ARGON2_ITERATIONS", 1, 100); err != nil {
+		return nil, err
+	}
+
+	if cfg.Argon2Parallelism, err = src.int("ARGON2_PARALLELISM", 1, 255); err != nil {
+		return nil, err
+	}
+
+	if cfg.BcryptCost, err = src.int("BCRYPT_COST", 4, 31); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginAttemptStore != "mongo" && cfg.LoginAttemptStore != "memory" {
+		return nil, fmt.Errorf("invalid LOGIN_ATTEMPT_STORE %q: must be mongo or memory", cfg.LoginAttemptStore)
+	}
+
+	if cfg.LoginMaxFailuresPerUser, err = src.int("LOGIN_MAX_FAILURES_PER_USER", 1, 1000); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginMaxFailuresPerIP, err = src.int("LOGIN_MAX_FAILURES_PER_IP", 1, 100000); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginFailureWindow, err = src.duration("LOGIN_FAILURE_WINDOW"); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginLockoutBase, err = src.duration("LOGIN_LOCKOUT_BASE"); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginLockoutMax, err = src.duration("LOGIN_LOCKOUT_MAX"); err != nil {
+		return nil, err
+	}
+
+	if cfg.LoginLockoutMax < cfg.LoginLockoutBase {
+		return nil, fmt.Errorf("invalid LOGIN_LOCKOUT_MAX setting: must not be lower than LOGIN_LOCKOUT_BASE")
+	}
+
+	if cfg.RateLimitAuth, err = src.rateLimit("RATE_LIMIT_AUTH"); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimitNews, err = src.rateLimit("RATE_LIMIT_NEWS"); err != nil {
+		return nil, err
+	}
+
+	if cfg.RateLimitDefault, err = src.rateLimit("RATE_LIMIT_DEFAULT"); err != nil {
+		return nil, err
+	}
+
+	if cfg.TracingExporter != "none" && cfg.TracingExporter != "stdout" && cfg.TracingExporter != "otlp" {
+		return nil, fmt.Errorf("invalid TRACING_EXPORTER %q: must be none, stdout or otlp", cfg.TracingExporter)
+	}
+
+	if cfg.TracingOTLPInsecure, err = src.bool("TRACING_OTLP_INSECURE"); err != nil {
+		return nil, err
+	}
+
+	if cfg.TracingSampleRatio, err = src.ratio("TRACING_SAMPLE_RATIO"); err != nil {
+		return nil, err
+	}
+
+	if src.string("SCHEDULER_INTERVAL") != "off" {
+		if cfg.SchedulerInterval, err = src.duration("SCHEDULER_INTERVAL"); err != nil {
+			return nil, err
+		}
+	}
+
+	if src.string("SECRETS_RELOAD_INTERVAL") != "off" {
+		if cfg.SecretsReloadInterval, err = src.duration("SECRETS_RELOAD_INTERVAL"); err != nil {
+			return nil, err
+		}
 	}
 
 	return cfg, nil
 }
 
-func getEnv(key string, defaultVal string) string {
-	if val, ok := os.LookupEnv(key); ok && val != "" {
-		return val
+// Values returns the effective value of every setting and where it comes from
+func (c *Config) Values() []Value {
+	return c.values
+}
+
+// Print writes the effective configuration to w, one KEY=value line per setting, with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	for _, v := range c.values {
+		if _, err := fmt.Fprintf(w, "%s=%s (%s)\n", v.Key, v.Redacted(), v.Origin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The certificate and the key must be set together and be readable
+func checkTLSFiles(certFile string, keyFile string) error {
+	if certFile == "" && keyFile == "" {
+		return nil
+	}
+	if certFile == "" || keyFile == "" {
+		return fmt.Errorf("invalid HTTP_TLS_CERT_FILE and HTTP_TLS_KEY_FILE settings: both must be set to serve HTTPS")
+	}
+
+	for _, path := range []string{certFile, keyFile} {
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("invalid TLS file %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func parseLogLevel(key string, val string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(val)); err != nil {
+		return 0, fmt.Errorf("invalid %s setting: must be debug, info, warn or error", key)
+	}
+	return level, nil
+}
+
+// Parse per package log levels with the format "news=debug,user=warn"
+func parseLogPackageLevels(val string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	if val == "" {
+		return levels, nil
+	}
+
+	for _, entry := range strings.Split(val, ",") {
+		pkg, levelStr, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found || pkg == "" {
+			return nil, fmt.Errorf("invalid LOG_PACKAGE_LEVELS setting: entries must be package=level")
+		}
+		level, err := parseLogLevel("LOG_PACKAGE_LEVELS", levelStr)
+		if err != nil {
+			return nil, err
+		}
+		levels[pkg] = level
+	}
+
+	return levels, nil
+}
+
+// Parse trusted proxies with the format "10.0.0.1,192.168.0.0/16", empty means no proxy is trusted
+func parseTrustedProxies(val string) ([]string, error) {
+	if val == "" {
+		return nil, nil
+	}
+
+	var proxies []string
+	for _, entry := range strings.Split(val, ",") {
+		proxy := strings.TrimSpace(entry)
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("invalid TRUSTED_PROXIES setting: %q is not an IP or a CIDR", proxy)
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// Parse signing keys with the format "kid1:secret1,kid2:secret2". Keeping
+// old keys in the list allows rotating the active key without invalidating
+// tokens that have already been issued.
+func parseJWTKeys(val string) (map[string]string, error) {
+	keys := make(map[string]string)
+	if val == "" {
+		return keys, nil
+	}
+
+	for _, entry := range strings.Split(val, ",") {
+		kid, secret, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || kid == "" {
+			return nil, fmt.Errorf("invalid JWT_KEYS setting: entries must be kid:secret")
+		}
+		if len(secret) < minJWTSecretLength {
+			return nil, fmt.Errorf("invalid JWT_KEYS setting: secret for key %q must be at least %d bytes", kid, minJWTSecretLength)
+		}
+		if _, exists := keys[kid]; exists {
+			return nil, fmt.Errorf("invalid JWT_KEYS setting: duplicated key %q", kid)
+		}
+		keys[kid] = secret
+	}
+
+	return keys, nil
+}
+
+// Choose the key used to sign new tokens, it can be omitted when there is only one key
+func activeJWTKeyID(keys map[string]string, kid string) (string, error) {
+	if kid == "" {
+		if len(keys) > 1 {
+			return "", fmt.Errorf("missing JWT_ACTIVE_KID setting: required when JWT_KEYS has several keys")
+		}
+		for only := range keys {
+			return only, nil
+		}
+		return "", nil
+	}
+
+	if _, ok := keys[kid]; !ok {
+		return "", fmt.Errorf("invalid JWT_ACTIVE_KID setting: key %q is not in JWT_KEYS", kid)
 	}
-	return defaultVal
+	return kid, nil
 }
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// SecretProvider resolves the value of secret settings such as DB_DSN
+type SecretProvider interface {
+	// Name is shown as the origin of the secrets in the effective configuration
+	Name() string
+	// Secret returns the value of key, found is false when the provider does not have it
+	Secret(key string) (value string, found bool, err error)
+}
+
+// EnvSecretProvider reads secrets from environment variables named like the setting
+type EnvSecretProvider struct {
+	// LookupEnv defaults to os.LookupEnv
+	LookupEnv func(string) (string, bool)
+}
+
+func (p EnvSecretProvider) Name() string {
+	return originEnv
+}
+
+func (p EnvSecretProvider) Secret(key string) (string, bool, error) {
+	val, ok := lookupEnv(p.LookupEnv)(key)
+	return val, ok && val != "", nil
+}
+
+// FileSecretProvider reads secrets from the file named by the <KEY>_FILE
+// environment variable, e.g. DB_DSN_FILE=/run/secrets/db_dsn for Docker secrets
+type FileSecretProvider struct {
+	// LookupEnv defaults to os.LookupEnv
+	LookupEnv func(string) (string, bool)
+}
+
+func (p FileSecretProvider) Name() string {
+	return "secret file"
+}
+
+func (p FileSecretProvider) Secret(key string) (string, bool, error) {
+	path, ok := lookupEnv(p.LookupEnv)(key + "_FILE")
+	if !ok || path == "" {
+		return "", false, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s_FILE: %w", key, err)
+	}
+
+	// Editors and "echo" usually leave a trailing newline
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+func lookupEnv(lookup func(string) (string, bool)) func(string) (string, bool) {
+	if lookup == nil {
+		return os.LookupEnv
+	}
+	return lookup
+}
+
+// Resolve a secret with the first provider that has it. Secrets must come from a single
+// provider, otherwise it would not be obvious which one is used.
+func resolveSecret(providers []SecretProvider, key string) (string, string, error) {
+	var value, origin string
+	for _, provider := range providers {
+		val, found, err := provider.Secret(key)
+		if err != nil {
+			return "", "", err
+		}
+		if !found {
+			continue
+		}
+		if origin != "" {
+			return "", "", fmt.Errorf("invalid %s setting: set by both %s and %s", key, origin, provider.Name())
+		}
+		value, origin = val, provider.Name()
+	}
+	return value, origin, nil
+}
+
+// WatchSecrets checks the secret providers every SecretsReloadInterval until ctx
+// is done and calls onChange with the new value of every secret that was rotated.
+// Secrets set by flags or by the config file are not watched.
+func (c *Config) WatchSecrets(ctx context.Context, onChange func(key string, value string)) {
+	if c.SecretsReloadInterval <= 0 {
+		return
+	}
+
+	current := make(map[string]string)
+	for _, v := range c.values {
+		if v.secret && v.Origin != originFlag && v.Origin != originFile && v.Origin != originDefault {
+			current[v.Key] = v.Value
+		}
+	}
+	if len(current) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(c.SecretsReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for key, old := range current {
+			val, _, err := resolveSecret(c.secrets, key)
+			if err != nil {
+				slog.Error("failed to reload secret", slog.String("key", key), slog.Any("error", err))
+				continue
+			}
+			if val == "" || val == old {
+				continue
+			}
+
+			slog.Info("secret rotated", slog.String("key", key))
+			current[key] = val
+			onChange(key, val)
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Where the value of a setting comes from, from the lowest to the highest precedence
+const (
+	originDefault = "default"
+	originFile    = "file"
+	originEnv     = "env"
+	originFlag    = "flag"
+)
+
+// Value printed instead of secrets
+const redacted = "REDACTED"
+
+// setting describes a configuration key. Keys are named like environment
+// variables, nested keys in config files and flags are mapped to them
+// (e.g. "session: {ttl: 1h}" and "-session-ttl=1h" both set SESSION_TTL).
+type setting struct {
+	key    string
+	def    string
+	secret bool
+	usage  string
+}
+
+var settings = []setting{
+	{key: "HTTP_PORT", def: "8000", usage: "port the HTTP server listens on"},
+	{key: "HTTP_READ_TIMEOUT", def: "15s", usage: "maximum duration to read a request"},
+	{key: "HTTP_WRITE_TIMEOUT", def: "30s", usage: "maximum duration to write a response"},
+	{key: "HTTP_IDLE_TIMEOUT", def: "60s", usage: "maximum duration of idle keep-alive connections"},
+	{key: "HTTP_SHUTDOWN_TIMEOUT", def: "10s", usage: "maximum duration to wait for requests on shutdown"},
+	{key: "HTTP_TLS_CERT_FILE", usage: "TLS certificate, HTTPS is served when it is set"},
+	{key: "HTTP_TLS_KEY_FILE", usage: "TLS private key"},
+	{key: "TRUSTED_PROXIES", usage: "IPs or CIDRs of the reverse proxies whose X-Forwarded-For is trusted, e.g. 10.0.0.0/8"},
+
+	{key: "DB_DSN", secret: true, usage: "PostgreSQL connection string"},
+	{key: "DB_MAX_OPEN_CONNS", def: "25", usage: "maximum number of open PostgreSQL connections"},
+	{key: "DB_MAX_IDLE_CONNS", def: "25", usage: "maximum number of idle PostgreSQL connections"},
+	{key: "DB_CONN_MAX_LIFETIME", def: "30m", usage: "maximum lifetime of a PostgreSQL connection"},
+	{key: "DB_CONNECT_TIMEOUT", def: "5s", usage: "timeout of the initial PostgreSQL ping"},
+	{key: "MIGRATE_ON_STARTUP", def: "false", usage: "apply the pending PostgreSQL migrations on startup"},
+	{key: "SCHEDULER_INTERVAL", def: "1m", usage: "how often scheduled articles are published, or off"},
+
+	{key: "MONGODB_URI", secret: true, usage: "MongoDB connection string"},
+	{key: "MONGODB_DATABASE", def: "app", usage: "MongoDB database"},
+	{key: "MONGODB_USERS_COLLECTION", def: "users", usage: "MongoDB collection of the users"},
+	{key: "MONGODB_SESSIONS_COLLECTION", def: "sessions", usage: "MongoDB collection of the sessions"},
+	{key: "MONGODB_REFRESH_TOKENS_COLLECTION", def: "refresh_tokens", usage: "MongoDB collection of the refresh tokens"},
+	{key: "MONGODB_LOGIN_ATTEMPTS_COLLECTION", def: "login_attempts", usage: "MongoDB collection of the failed login attempts"},
+	{key: "MONGODB_MAX_POOL_SIZE", def: "100", usage: "maximum number of MongoDB connections"},
+	{key: "MONGODB_CONNECT_TIMEOUT", def: "10s", usage: "timeout of the initial MongoDB ping and index creation"},
+
+	{key: "LOG_FORMAT", def: "text", usage: "log format, text or json"},
+	{key: "LOG_LEVEL", def: "info", usage: "minimum log level"},
+	{key: "LOG_PACKAGE_LEVELS", usage: "log levels per package, e.g. news=debug,user=warn"},
+
+	{key: "SESSION_STORE", def: "mongo", usage: "session store, mongo or memory"},
+	{key: "SESSION_TTL", def: "24h", usage: "lifetime of the login sessions"},
+	{key: "SESSION_COOKIE_SECURE", def: "true", usage: "only send the session cookie over HTTPS"},
+
+	{key: "JWT_KEYS", secret: true, usage: "JWT signing keys, kid1:secret1,kid2:secret2"},
+	{key: "JWT_ACTIVE_KID", usage: "JWT key used to sign new tokens"},
+	{key: "JWT_ACCESS_TTL", def: "15m", usage: "lifetime of the access tokens"},
+	{key: "JWT_REFRESH_TTL", def: "720h", usage: "lifetime of the refresh tokens"},
+
+	{key: "PASSWORD_HASH_ALGORITHM", def: "argon2id", usage: "password hashing algorithm, argon2id or bcrypt"},
+	{key: "ARGON2_MEMORY_KIB", def: "65536", usage: "argon2id memory in KiB"},
+	{key: "ARGON2_ITERATIONS", def: "3", usage: "argon2id iterations"},
+	{key: "ARGON2_PARALLELISM", def: "2", usage: "argon2id parallelism"},
+	{key: "BCRYPT_COST", def: "12", usage: "bcrypt cost"},
+
+	{key: "LOGIN_ATTEMPT_STORE", def: "mongo", usage: "failed login store, mongo or memory"},
+	{key: "LOGIN_MAX_FAILURES_PER_USER", def: "5", usage: "failed logins before locking a username"},
+	{key: "LOGIN_MAX_FAILURES_PER_IP", def: "20", usage: "failed logins before locking a client IP"},
+	{key: "LOGIN_FAILURE_WINDOW", def: "15m", usage: "window in which failed logins are counted"},
+	{key: "LOGIN_LOCKOUT_BASE", def: "1m", usage: "duration of the first lockout"},
+	{key: "LOGIN_LOCKOUT_MAX", def: "1h", usage: "maximum duration of a lockout"},
+
+	{key: "RATE_LIMIT_AUTH", def: "10/1m", usage: "rate limit of the login routes, <requests>/<period> or off"},
+	{key: "RATE_LIMIT_NEWS", def: "300/1m", usage: "rate limit of the news routes, <requests>/<period> or off"},
+	{key: "RATE_LIMIT_DEFAULT", def: "60/1m", usage: "rate limit of the other routes, <requests>/<period> or off"},
+
+	{key: "TRACING_EXPORTER", def: "none", usage: "trace exporter, none, stdout or otlp"},
+	{key: "TRACING_SERVICE_NAME", def: "news_service", usage: "service name reported in the traces"},
+	{key: "TRACING_OTLP_ENDPOINT", def: "localhost:4318", usage: "OTLP/HTTP collector endpoint"},
+	{key: "TRACING_OTLP_INSECURE", def: "false", usage: "send traces to the collector without TLS"},
+	{key: "TRACING_SAMPLE_RATIO", def: "1", usage: "share of the new traces that are recorded"},
+
+	{key: "SECRETS_RELOAD_INTERVAL", def: "30s", usage: "how often rotated secrets are reloaded, or off"},
+}
+
+// Value is the effective value of a setting
+type Value struct {
+	Key    string
+	Value  string
+	Origin string
+	secret bool
+}
+
+// source merges the values of the defaults, the config file, the environment
+// and the command-line flags, each one taking precedence over the previous ones.
+// Secrets are taken from the secret providers instead of the environment.
+type source struct {
+	file    map[string]string
+	flags   map[string]string
+	env     func(string) (string, bool)
+	secrets map[string]Value
+}
+
+// Build the source from the command-line arguments. The config file is taken
+// from the -config flag or the CONFIG_FILE environment variable.
+func newSource(args []string, env func(string) (string, bool), providers []SecretProvider) (*source, bool, error) {
+	fs := flag.NewFlagSet("news_service", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", "", "YAML or TOML config file")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	for _, s := range settings {
+		fs.String(flagName(s.key), "", s.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	src := &source{file: map[string]string{}, flags: map[string]string{}, env: env, secrets: map[string]Value{}}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" && f.Name != "print-config" {
+			src.flags[keyName(f.Name)] = f.Value.String()
+		}
+	})
+
+	path := *configFile
+	if path == "" {
+		path, _ = env("CONFIG_FILE")
+	}
+	if path != "" {
+		file, err := readFile(path)
+		if err != nil {
+			return nil, false, err
+		}
+		src.file = file
+	}
+
+	for _, setting := range settings {
+		if !setting.secret {
+			continue
+		}
+		val, origin, err := resolveSecret(providers, setting.key)
+		if err != nil {
+			return nil, false, err
+		}
+		if origin != "" {
+			src.secrets[setting.key] = Value{Key: setting.key, Value: val, Origin: origin, secret: true}
+		}
+	}
+
+	return src, *printConfig, nil
+}
+
+// Read a config file, its format is chosen by extension
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var tree map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &tree)
+	case ".toml":
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported config file %q: must be .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %q: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	if err := flatten("", tree, values); err != nil {
+		return nil, fmt.Errorf("invalid config file %q: %w", path, err)
+	}
+	return values, nil
+}
+
+// Turn nested tables into keys joined by underscores, e.g. db.max_open_conns into DB_MAX_OPEN_CONNS
+func flatten(prefix string, tree map[string]any, values map[string]string) error {
+	for name, value := range tree {
+		key := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(key, v, values); err != nil {
+				return err
+			}
+		case []any, nil:
+			return fmt.Errorf("%s must be a string, a number or a boolean", key)
+		default:
+			if _, ok := find(key); !ok {
+				return fmt.Errorf("unknown setting %s", key)
+			}
+			values[key] = fmt.Sprint(v)
+		}
+	}
+	return nil
+}
+
+func find(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// HTTP_PORT is set with the -http-port flag
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func keyName(flag string) string {
+	return strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// Resolve the value of a setting, empty values are ignored
+func (s *source) lookup(key string) (string, string) {
+	setting, _ := find(key)
+	if val, ok := s.flags[key]; ok && val != "" {
+		return val, originFlag
+	}
+	if setting.secret {
+		if secret, ok := s.secrets[key]; ok {
+			return secret.Value, secret.Origin
+		}
+	} else if val, ok := s.env(key); ok && val != "" {
+		return val, originEnv
+	}
+	if val, ok := s.file[key]; ok && val != "" {
+		return val, originFile
+	}
+	return setting.def, originDefault
+}
+
+// Effective values of every setting, sorted by key
+func (s *source) values() []Value {
+	values := make([]Value, 0, len(settings))
+	for _, setting := range settings {
+		val, origin := s.lookup(setting.key)
+		values = append(values, Value{Key: setting.key, Value: val, Origin: origin, secret: setting.secret})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Key < values[j].Key })
+	return values
+}
+
+func (s *source) string(key string) string {
+	val, _ := s.lookup(key)
+	return val
+}
+
+func (s *source) duration(key string) (time.Duration, error) {
+	duration, err := time.ParseDuration(s.string(key))
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid %s setting: must be a positive duration", key)
+	}
+	return duration, nil
+}
+
+func (s *source) int(key string, minVal int, maxVal int) (int, error) {
+	parsed, err := strconv.Atoi(s.string(key))
+	if err != nil || parsed < minVal || parsed > maxVal {
+		return 0, fmt.Errorf("invalid %s setting: must be an integer between %d and %d", key, minVal, maxVal)
+	}
+	return parsed, nil
+}
+
+func (s *source) ratio(key string) (float64, error) {
+	parsed, err := strconv.ParseFloat(s.string(key), 64)
+	if err != nil || parsed < 0 || parsed > 1 {
+		return 0, fmt.Errorf("invalid %s setting: must be a number between 0 and 1", key)
+	}
+	return parsed, nil
+}
+
+func (s *source) bool(key string) (bool, error) {
+	parsed, err := strconv.ParseBool(s.string(key))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s setting: must be a boolean", key)
+	}
+	return parsed, nil
+}
+
+// Parse rate limits with the format "<requests>/<period>" (e.g. "10/1m"), "off" disables them
+func (s *source) rateLimit(key string) (RateLimit, error) {
+	val := s.string(key)
+	if val == "off" {
+		return RateLimit{}, nil
+	}
+
+	requestsStr, periodStr, found := strings.Cut(val, "/")
+	requests, err := strconv.Atoi(requestsStr)
+	if !found || err != nil || requests <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid %s setting: must be <requests>/<period> or off", key)
+	}
+
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid %s setting: must be <requests>/<period> or off", key)
+	}
+
+	return RateLimit{Requests: requests, Period: period}, nil
+}
+
+// Redacted returns the value to print, secrets are hidden. Only the passwords
+// of connection strings are hidden so the host and database can be checked,
+// secrets that do not parse back to the same URL are hidden entirely.
+func (v Value) Redacted() string {
+	if !v.secret || v.Value == "" {
+		return v.Value
+	}
+
+	u, err := url.Parse(v.Value)
+	if err != nil || u.Host == "" || u.Opaque != "" || u.Fragment != "" || u.String() != v.Value {
+		return redacted
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return redacted
+	}
+
+	if u.User != nil {
+		if _, hasPassword := u.User.Password(); hasPassword {
+			u.User = url.UserPassword(u.User.Username(), redacted)
+		}
+	}
+	// e.g. password and sslpassword in PostgreSQL, tlsCertificateKeyFilePassword in MongoDB
+	for key := range query {
+		if strings.Contains(strings.ToLower(key), "password") {
+			query[key] = []string{redacted}
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.String()
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log/slog"
+	"sync"
+
+	"github.com/lib/pq"
+)
+
+// Connector opens PostgreSQL connections with a DSN that can be replaced at
+// runtime, so rotated credentials are used without restarting the service
+type Connector struct {
+	mu  sync.RWMutex
+	dsn string
+}
+
+// Constructor
+func NewConnector(dsn string) *Connector {
+	return &Connector{dsn: dsn}
+}
+
+func (c *Connector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.mu.RLock()
+	dsn := c.dsn
+	c.mu.RUnlock()
+
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *Connector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// Rotate checks that dsn works and switches db to it. Idle connections are
+// closed so they are reopened with the new credentials, connections in use
+// are replaced once they reach their maximum lifetime.
+func (c *Connector) Rotate(ctx context.Context, db *sql.DB, dsn string, maxIdleConns int) error {
+	// Check the new credentials before dropping connections that work, the current ones are kept otherwise
+	conn, err := (&Connector{dsn: dsn}).Connect(ctx)
+	if err != nil {
+		return err
+	}
+	if err := conn.Close(); err != nil {
+		slog.Warn("failed to close database check connection", slog.Any("error", err))
+	}
+
+	c.mu.Lock()
+	c.dsn = dsn
+	c.mu.Unlock()
+
+	db.SetMaxIdleConns(0)
+	db.SetMaxIdleConns(maxIdleConns)
+
+	slog.Info("database credentials rotated")
+	return nil
+}
@@ -1,23 +1,144 @@
 package health
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/ManuelJNunez/news_service/internal/logging"
 	"github.com/gin-gonic/gin"
 )
 
-type Handler struct{}
+// Timeout used by checkers that do not set their own
+const defaultCheckTimeout = 2 * time.Second
 
-func NewHandler() *Handler {
-	return &Handler{}
+// Errors reported by failed checks
+const (
+	checkUnavailable = "unavailable"
+	checkTimeout     = "timeout"
+)
+
+// Check status values
+const (
+	statusOK       = "ok"
+	statusDegraded = "degraded"
+	statusFail     = "fail"
+)
+
+// Checker verifies that a dependency is reachable
+type Checker struct {
+	Name string
+	// The service is not ready when a critical check fails, other failures only degrade it
+	Critical bool
+	Timeout  time.Duration
+	Check    func(ctx context.Context) error
+}
+
+// CheckOutput for API responses
+type CheckOutput struct {
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// ReadinessOutput for API responses
+type ReadinessOutput struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckOutput `json:"checks"`
+}
+
+type Handler struct {
+	checkers []Checker
+}
+
+func NewHandler(checkers ...Checker) *Handler {
+	return &Handler{checkers: checkers}
 }
 
 func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
-	rg.GET("/health", h.healthCheck)
+	rg.GET("/health", h.liveness)
+	rg.GET("/health/live", h.liveness)
+	rg.GET("/health/ready", h.readiness)
 }
 
-func (h *Handler) healthCheck(c *gin.Context) {
+// The process is alive as long as it can answer
+func (h *Handler) liveness(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
+		"status": statusOK,
 	})
 }
+
+// The process is ready when every critical dependency answers in time
+func (h *Handler) readiness(c *gin.Context) {
+	output := h.runChecks(c.Request.Context())
+
+	status := http.StatusOK
+	if output.Status == statusFail {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, output)
+}
+
+// Run every check concurrently, each one with its own timeout
+func (h *Handler) runChecks(ctx context.Context) ReadinessOutput {
+	results := make([]CheckOutput, len(h.checkers))
+
+	var wg sync.WaitGroup
+	for i, checker := range h.checkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = runCheck(ctx, checker)
+		}()
+	}
+	wg.Wait()
+
+	output := ReadinessOutput{Status: statusOK, Checks: make(map[string]CheckOutput, len(results))}
+	for i, result := range results {
+		output.Checks[h.checkers[i].Name] = result
+		if result.Status == statusOK {
+			continue
+		}
+
+		if result.Critical {
+			output.Status = statusFail
+		} else if output.Status == statusOK {
+			output.Status = statusDegraded
+		}
+	}
+	return output
+}
+
+// Run a single check, the response only tells whether it failed or timed out so
+// connection details in the error are kept out of it
+func runCheck(ctx context.Context, checker Checker) CheckOutput {
+	logger := logging.FromContext(ctx)
+
+	timeout := checker.Timeout
+	if timeout <= 0 {
+		timeout = defaultCheckTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := checker.Check(ctx)
+	result := CheckOutput{
+		Status:    statusOK,
+		Critical:  checker.Critical,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		logger.Warn("health check failed", slog.String("check", checker.Name), slog.Any("error", err))
+		result.Status = statusFail
+		result.Error = checkUnavailable
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = checkTimeout
+		}
+	}
+	return result
+}
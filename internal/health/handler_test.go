@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupRouter(checkers ...Checker) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	RegisterRoutes(r.Group(""), NewHandler(checkers...))
+	return r
+}
+
+func ok(_ context.Context) error {
+	return nil
+}
+
+func failing(_ context.Context) error {
+	return errors.New("connection refused")
+}
+
+func getReadiness(t *testing.T, router *gin.Engine) (int, ReadinessOutput) {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/health/ready", nil)
+	router.ServeHTTP(w, req)
+
+	var resp ReadinessOutput
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return w.Code, resp
+}
+
+func TestLivenessIgnoresChecks(t *testing.T) {
+	router := setupRouter(Checker{Name: "postgres", Critical: true, Check: failing})
+
+	for _, path := range []string{"/health", "/health/live"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+}
+
+func TestReadinessAllChecksPass(t *testing.T) {
+	router := setupRouter(
+		Checker{Name: "postgres", Critical: true, Check: ok},
+		Checker{Name: "mongodb", Critical: true, Check: ok},
+	)
+
+	status, resp := getReadiness(t, router)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, statusOK, resp.Status)
+	assert.Len(t, resp.Checks, 2)
+	assert.Equal(t, statusOK, resp.Checks["mongodb"].Status)
+}
+
+func TestReadinessCriticalCheckFails(t *testing.T) {
+	router := setupRouter(
+		Checker{Name: "postgres", Critical: true, Check: failing},
+		Checker{Name: "mongodb", Critical: true, Check: ok},
+	)
+
+	status, resp := getReadiness(t, router)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, statusFail, resp.Status)
+	assert.Equal(t, "unavailable", resp.Checks["postgres"].Error)
+}
+
+func TestReadinessNonCriticalCheckFails(t *testing.T) {
+	router := setupRouter(
+		Checker{Name: "postgres", Critical: true, Check: ok},
+		Checker{Name: "cache", Critical: false, Check: failing},
+	)
+
+	status, resp := getReadiness(t, router)
+
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, statusDegraded, resp.Status)
+}
+
+func TestReadinessCheckTimeout(t *testing.T) {
+	slow := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	router := setupRouter(Checker{Name: "postgres", Critical: true, Timeout: 10 * time.Millisecond, Check: slow})
+
+	status, resp := getReadiness(t, router)
+
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Equal(t, "timeout", resp.Checks["postgres"].Error)
+	assert.GreaterOrEqual(t, resp.Checks["postgres"].LatencyMS, float64(10))
+}
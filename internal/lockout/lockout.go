@@ -0,0 +1,163 @@
+package lockout
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+)
+
+// Record of the recent failed login attempts for a key (a username or a client IP)
+type Record struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LockedUntil time.Time `bson:"locked_until"`
+	ExpiresAt   time.Time `bson:"expires_at"`
+}
+
+// Store keeps failed attempt records. Records whose ExpiresAt is not after
+// now must be treated as missing.
+type Store interface {
+	// Get the record of key, a zero Record is returned when there is none
+	Get(ctx context.Context, key string, now time.Time) (Record, error)
+	// Increment atomically adds one failure to key, starting a new record if the previous one expired
+	Increment(ctx context.Context, key string, now time.Time, expiresAt time.Time) (Record, error)
+	// Lock key until the given time
+	Lock(ctx context.Context, key string, until time.Time, expiresAt time.Time) error
+	// Reset forgets every failure of key
+	Reset(ctx context.Context, key string) error
+}
+
+// Policy deciding when and for how long logins are locked
+type Policy struct {
+	// Failures allowed for a username before it gets locked
+	MaxFailuresPerUser int
+	// Failures allowed from a client IP, across usernames, before it gets locked
+	MaxFailuresPerIP int
+	// Failures are forgotten after this time without new ones
+	Window time.Duration
+	// The first lock lasts BaseLockout, every further failure doubles it up to MaxLockout
+	BaseLockout time.Duration
+	MaxLockout  time.Duration
+}
+
+// Guard tracks failed logins and tells when a login must be rejected
+type Guard struct {
+	store  Store
+	policy Policy
+	now    func() time.Time
+}
+
+// Constructor
+func NewGuard(store Store, policy Policy) *Guard {
+	slog.Info("login guard initialized",
+		slog.Int("max_failures_per_user", policy.MaxFailuresPerUser),
+		slog.Int("max_failures_per_ip", policy.MaxFailuresPerIP),
+		slog.Duration("base_lockout", policy.BaseLockout),
+	)
+	return &Guard{store: store, policy: policy, now: time.Now}
+}
+
+// Check returns how long the client has to wait before trying to log in
+// again, zero means the attempt is allowed
+func (g *Guard) Check(ctx context.Context, username string, clientIP string) (time.Duration, error) {
+	logger := logging.FromContext(ctx)
+
+	now := g.now()
+	var retryAfter time.Duration
+
+	for _, key := range []string{userKey(username), ipKey(clientIP)} {
+		record, err := g.store.Get(ctx, key, now)
+		if err != nil {
+			return 0, err
+		}
+		if wait := record.LockedUntil.Sub(now); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if retryAfter > 0 {
+		logger.Warn("login blocked",
+			slog.String("event", "login_blocked"),
+			slog.String("username", username),
+			slog.String("client_ip", clientIP),
+			slog.Duration("retry_after", retryAfter),
+		)
+	}
+	return retryAfter, nil
+}
+
+// Failure records a failed login and locks the username or the client IP when
+// they exceed the policy limits
+func (g *Guard) Failure(ctx context.Context, username string, clientIP string) error {
+	if err := g.fail(ctx, userKey(username), g.policy.MaxFailuresPerUser, username, clientIP); err != nil {
+		return err
+	}
+	return g.fail(ctx, ipKey(clientIP), g.policy.MaxFailuresPerIP, username, clientIP)
+}
+
+// Success forgets the failures of the username. Client IP failures are kept,
+// otherwise an attacker could reset them by logging in to their own account.
+func (g *Guard) Success(ctx context.Context, username string) error {
+	return g.store.Reset(ctx, userKey(username))
+}
+
+func (g *Guard) fail(ctx context.Context, key string, maxFailures int, username string, clientIP string) error {
+	logger := logging.FromContext(ctx)
+
+	now := g.now()
+	record, err := g.store.Increment(ctx, key, now, now.Add(g.policy.Window))
+	if err != nil {
+		return err
+	}
+
+	logger.Warn("login failed",
+		slog.String("event", "login_failed"),
+		slog.String("key", key),
+		slog.String("username", username),
+		slog.String("client_ip", clientIP),
+		slog.Int("failures", record.Failures),
+	)
+
+	if record.Failures < maxFailures {
+		return nil
+	}
+
+	// Lock progressively: every failure over the limit doubles the lock duration
+	lockout := g.lockoutFor(record.Failures - maxFailures)
+	until := now.Add(lockout)
+	if err := g.store.Lock(ctx, key, until, until.Add(g.policy.Window)); err != nil {
+		return err
+	}
+
+	logger.Warn("login locked",
+		slog.String("event", "login_locked"),
+		slog.String("key", key),
+		slog.String("username", username),
+		slog.String("client_ip", clientIP),
+		slog.Int("failures", record.Failures),
+		slog.Duration("lockout", lockout),
+	)
+	return nil
+}
+
+func (g *Guard) lockoutFor(excess int) time.Duration {
+	lockout := g.policy.BaseLockout
+	for range excess {
+		lockout *= 2
+		if lockout >= g.policy.MaxLockout {
+			return g.policy.MaxLockout
+		}
+	}
+	return min(lockout, g.policy.MaxLockout)
+}
+
+func userKey(username string) string {
+	return "user:" + strings.ToLower(username)
+}
+
+func ipKey(clientIP string) string {
+	return "ip:" + clientIP
+}
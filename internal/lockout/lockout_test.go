@@ -0,0 +1,128 @@
+package lockout
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGuard(now *time.Time) *Guard {
+	guard := NewGuard(NewMemoryStore(), Policy{
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   5,
+		Window:             15 * time.Minute,
+		BaseLockout:        time.Minute,
+		MaxLockout:         5 * time.Minute,
+	})
+	guard.now = func() time.Time { return *now }
+	return guard
+}
+
+func fail(t *testing.T, guard *Guard, username string, clientIP string, times int) {
+	for range times {
+		require.NoError(t, guard.Failure(context.Background(), username, clientIP))
+	}
+}
+
+func TestGuardLocksUserAfterMaxFailures(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+
+	fail(t, guard, "alice", "10.0.0.1", 2)
+	retryAfter, err := guard.Check(ctx, "alice", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	fail(t, guard, "alice", "10.0.0.1", 1)
+	retryAfter, err = guard.Check(ctx, "ALICE", "10.0.0.2")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Once the lock expires the user can try again
+	now = now.Add(time.Minute)
+	retryAfter, err = guard.Check(ctx, "alice", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestGuardLockoutIsProgressive(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+
+	expected := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+	for _, lockout := range expected {
+		fail(t, guard, "alice", "", 1)
+		if lockout == time.Minute {
+			fail(t, guard, "alice", "", 2)
+		}
+
+		retryAfter, err := guard.Check(ctx, "alice", "")
+		require.NoError(t, err)
+		assert.Equal(t, lockout, retryAfter)
+	}
+}
+
+func TestGuardLocksClientIPAcrossUsernames(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(&now)
+
+	for _, username := range []string{"a", "b", "c", "d", "e"} {
+		fail(t, guard, username, "10.0.0.1", 1)
+	}
+
+	retryAfter, err := guard.Check(context.Background(), "f", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, retryAfter)
+}
+
+func TestGuardSuccessResetsUserFailures(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(&now)
+	ctx := context.Background()
+
+	fail(t, guard, "alice", "10.0.0.1", 2)
+	require.NoError(t, guard.Success(ctx, "alice"))
+	fail(t, guard, "alice", "10.0.0.1", 2)
+
+	retryAfter, err := guard.Check(ctx, "alice", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestGuardForgetsFailuresAfterWindow(t *testing.T) {
+	now := time.Now()
+	guard := newTestGuard(&now)
+
+	fail(t, guard, "alice", "10.0.0.1", 2)
+	now = now.Add(16 * time.Minute)
+	fail(t, guard, "alice", "10.0.0.1", 2)
+
+	retryAfter, err := guard.Check(context.Background(), "alice", "10.0.0.1")
+	require.NoError(t, err)
+	assert.Zero(t, retryAfter)
+}
+
+func TestMemoryStoreSweepsExpiredRecords(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := store.Increment(ctx, "user:alice", now, now.Add(time.Minute))
+	require.NoError(t, err)
+	_, err = store.Increment(ctx, "user:bob", now, now.Add(time.Hour))
+	require.NoError(t, err)
+
+	// Records of keys that are never seen again are forgotten once they expire
+	_, err = store.Increment(ctx, "ip:192.0.2.1", now.Add(2*time.Minute), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	records := store.(*memoryStore).records
+	assert.NotContains(t, records, "user:alice")
+	assert.Contains(t, records, "user:bob")
+	assert.Contains(t, records, "ip:192.0.2.1")
+}
@@ -0,0 +1,96 @@
+package lockout
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Expired records of keys that are never seen again are removed this often
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	records   map[string]Record
+	lastSweep time.Time
+}
+
+// NewMemoryStore keeps records in process memory, they are lost on restart
+// and not shared between replicas.
+func NewMemoryStore() Store {
+	return &memoryStore{records: make(map[string]Record)}
+}
+
+func (s *memoryStore) Get(_ context.Context, key string, now time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.current(key, now), nil
+}
+
+func (s *memoryStore) Increment(_ context.Context, key string, now time.Time, expiresAt time.Time) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+	record := s.current(key, now)
+	record.Key = key
+	record.Failures++
+	record.ExpiresAt = later(record.ExpiresAt, expiresAt)
+	s.records[key] = record
+	return record, nil
+}
+
+func (s *memoryStore) Lock(_ context.Context, key string, until time.Time, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record := s.records[key]
+	record.Key = key
+	record.LockedUntil = until
+	record.ExpiresAt = later(record.ExpiresAt, expiresAt)
+	s.records[key] = record
+	return nil
+}
+
+func (s *memoryStore) Reset(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Get the record of key removing it if it has expired, callers must hold the lock
+func (s *memoryStore) current(key string, now time.Time) Record {
+	record, ok := s.records[key]
+	if !ok {
+		return Record{}
+	}
+	if !record.ExpiresAt.After(now) {
+		delete(s.records, key)
+		return Record{}
+	}
+	return record
+}
+
+// Forget the expired records, callers must hold the lock
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, record := range s.records {
+		if !record.ExpiresAt.After(now) {
+			delete(s.records, key)
+		}
+	}
+}
+
+func later(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
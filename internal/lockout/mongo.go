@@ -0,0 +1,91 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type mongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore keeps records in the given collection, so every replica
+// shares them. A TTL index on expires_at removes old records.
+func NewMongoStore(ctx context.Context, collection *mongo.Collection) (Store, error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		slog.Error("error creating login attempts TTL index", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &mongoStore{collection: collection}, nil
+}
+
+func (s *mongoStore) Get(ctx context.Context, key string, now time.Time) (Record, error) {
+	var record Record
+
+	// The TTL monitor runs periodically, so expired records are filtered out here too
+	err := s.collection.FindOne(ctx, bson.M{"_id": key, "expires_at": bson.M{"$gt": now}}).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return Record{}, nil
+	}
+	if err != nil {
+		slog.Error("error finding login attempts", slog.String("key", key), slog.Any("error", err))
+		return Record{}, err
+	}
+
+	return record, nil
+}
+
+func (s *mongoStore) Increment(ctx context.Context, key string, now time.Time, expiresAt time.Time) (Record, error) {
+	// A single pipeline update keeps the increment atomic: expired records
+	// start again from one failure and are unlocked
+	expired := bson.M{"$lte": bson.A{bson.M{"$ifNull": bson.A{"$expires_at", now}}, now}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures":     bson.M{"$cond": bson.A{expired, 1, bson.M{"$add": bson.A{"$failures", 1}}}},
+			"locked_until": bson.M{"$cond": bson.A{expired, time.Time{}, "$locked_until"}},
+			"expires_at": bson.M{"$cond": bson.A{
+				bson.M{"$gt": bson.A{bson.M{"$ifNull": bson.A{"$expires_at", now}}, expiresAt}},
+				"$expires_at",
+				expiresAt,
+			}},
+		}}},
+	}
+
+	var record Record
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&record)
+	if err != nil {
+		slog.Error("error recording failed login", slog.String("key", key), slog.Any("error", err))
+		return Record{}, err
+	}
+
+	return record, nil
+}
+
+func (s *mongoStore) Lock(ctx context.Context, key string, until time.Time, expiresAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"locked_until": until},
+		"$max": bson.M{"expires_at": expiresAt},
+	}
+	_, err := s.collection.UpdateOne(ctx, bson.M{"_id": key}, update, options.UpdateOne().SetUpsert(true))
+	if err != nil {
+		slog.Error("error locking login", slog.String("key", key), slog.Any("error", err))
+	}
+	return err
+}
+
+func (s *mongoStore) Reset(ctx context.Context, key string) error {
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LevelInput changes the base level, or the level of Package when it is set.
+// An empty Level removes the override of Package.
+type LevelInput struct {
+	Package string `json:"package"`
+	Level   string `json:"level"`
+}
+
+type Handler struct {
+	levels *Levels
+}
+
+func NewHandler(levels *Levels) *Handler {
+	return &Handler{levels: levels}
+}
+
+// RegisterAdminRoutes registers the routes to inspect and change the log levels at runtime
+func RegisterAdminRoutes(rg *gin.RouterGroup, h *Handler) {
+	rg.GET("/log-level", h.getLevels)
+	rg.PUT("/log-level", h.setLevel)
+	slog.Info("logging admin routes registered")
+}
+
+func (h *Handler) getLevels(c *gin.Context) {
+	c.JSON(http.StatusOK, h.levels.ToOutput())
+}
+
+func (h *Handler) setLevel(c *gin.Context) {
+	var input LevelInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	switch {
+	case input.Level == "" && input.Package == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "level is required"})
+		return
+	case input.Level == "":
+		h.levels.ResetPackage(input.Package)
+	default:
+		level, err := ParseLevel(input.Level)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if input.Package == "" {
+			h.levels.SetBase(level)
+		} else {
+			h.levels.SetPackage(input.Package, level)
+		}
+	}
+
+	FromContext(c.Request.Context()).Warn("log level changed", slog.String("package", input.Package), slog.String("level", input.Level))
+	c.JSON(http.StatusOK, h.levels.ToOutput())
+}
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Levels holds the minimum level of the log lines, which can be changed at runtime
+// and overridden per package (e.g. "news" or "user")
+type Levels struct {
+	mu       sync.RWMutex
+	base     slog.Level
+	packages map[string]slog.Level
+}
+
+// Constructor
+func NewLevels(base slog.Level, packages map[string]slog.Level) *Levels {
+	levels := &Levels{base: base, packages: make(map[string]slog.Level, len(packages))}
+	for pkg, level := range packages {
+		levels.packages[pkg] = level
+	}
+	return levels
+}
+
+// ParseLevel parses a level name such as "debug" or "warn"
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// Level returns the most verbose level enabled in any package, so that
+// handlers built with Levels as their Leveler do not drop overridden lines
+func (l *Levels) Level() slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	minLevel := l.base
+	for _, level := range l.packages {
+		minLevel = min(minLevel, level)
+	}
+	return minLevel
+}
+
+// For returns the minimum level of the lines logged by pkg
+func (l *Levels) For(pkg string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if level, ok := l.packages[pkg]; ok {
+		return level
+	}
+	return l.base
+}
+
+// SetBase changes the level of the packages without an override
+func (l *Levels) SetBase(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.base = level
+}
+
+// SetPackage overrides the level of pkg
+func (l *Levels) SetPackage(pkg string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.packages[pkg] = level
+}
+
+// ResetPackage removes the override of pkg, which goes back to the base level
+func (l *Levels) ResetPackage(pkg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.packages, pkg)
+}
+
+// LevelsOutput for API responses
+type LevelsOutput struct {
+	Level    string            `json:"level"`
+	Packages map[string]string `json:"packages"`
+}
+
+func (l *Levels) ToOutput() LevelsOutput {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	output := LevelsOutput{Level: l.base.String(), Packages: make(map[string]string, len(l.packages))}
+	for pkg, level := range l.packages {
+		output.Packages[pkg] = level.String()
+	}
+	return output
+}
+
+// levelHandler drops the records logged below the level of the package they come from
+type levelHandler struct {
+	next   slog.Handler
+	levels *Levels
+}
+
+// NewLevelHandler wraps next so the records are filtered with levels. next must
+// be built with levels as its Leveler, or with a level low enough to let every
+// record through.
+func NewLevelHandler(next slog.Handler, levels *Levels) slog.Handler {
+	return &levelHandler{next: next, levels: levels}
+}
+
+// The package is unknown at this point, so only records below every level are discarded
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.levels.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.levels.For(packageOf(r.PC)) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), levels: h.levels}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), levels: h.levels}
+}
+
+// Name of the package of the function that logged the record, cached by program counter
+var packageNames sync.Map
+
+func packageOf(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if pkg, ok := packageNames.Load(pc); ok {
+		return pkg.(string)
+	}
+
+	// Functions are named like "github.com/ManuelJNunez/news_service/internal/news.(*service).GetByID"
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	name := frame.Function
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	pkg, _, _ := strings.Cut(name, ".")
+
+	packageNames.Store(pc, pkg)
+	return pkg
+}
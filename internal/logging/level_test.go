@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newLeveledLogger(levels *Levels) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: levels})
+	return slog.New(NewLevelHandler(handler, levels)), &buf
+}
+
+func TestLevelHandlerUsesBaseLevel(t *testing.T) {
+	logger, buf := newLeveledLogger(NewLevels(slog.LevelInfo, nil))
+
+	logger.Debug("hidden")
+	logger.Info("shown")
+
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func TestLevelHandlerUsesPackageOverride(t *testing.T) {
+	levels := NewLevels(slog.LevelWarn, map[string]slog.Level{"logging": slog.LevelDebug})
+	logger, buf := newLeveledLogger(levels)
+
+	// The lines are logged from this package, so its override applies
+	logger.Debug("debug with override")
+	levels.ResetPackage("logging")
+	logger.Info("info without override")
+
+	assert.Contains(t, buf.String(), "debug with override")
+	assert.NotContains(t, buf.String(), "info without override")
+}
+
+func TestLevelHandlerIgnoresOtherPackages(t *testing.T) {
+	logger, buf := newLeveledLogger(NewLevels(slog.LevelInfo, map[string]slog.Level{"news": slog.LevelError}))
+
+	logger.Info("shown")
+
+	assert.Contains(t, buf.String(), "shown")
+}
+
+func TestLevelsLevelIsTheMostVerbose(t *testing.T) {
+	levels := NewLevels(slog.LevelWarn, map[string]slog.Level{"news": slog.LevelDebug, "user": slog.LevelError})
+
+	assert.Equal(t, slog.LevelDebug, levels.Level())
+	assert.Equal(t, slog.LevelError, levels.For("user"))
+	assert.Equal(t, slog.LevelWarn, levels.For("lockout"))
+}
+
+func setupAdminRouter(levels *Levels) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterAdminRoutes(router.Group("/admin"), NewHandler(levels))
+	return router
+}
+
+func putLevel(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetLevels(t *testing.T) {
+	router := setupAdminRouter(NewLevels(slog.LevelInfo, map[string]slog.Level{"news": slog.LevelDebug}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/log-level", nil)
+	router.ServeHTTP(w, req)
+
+	var output LevelsOutput
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &output))
+	assert.Equal(t, LevelsOutput{Level: "INFO", Packages: map[string]string{"news": "DEBUG"}}, output)
+}
+
+func TestSetLevels(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo, nil)
+	router := setupAdminRouter(levels)
+
+	w := putLevel(router, `{"level": "error"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelError, levels.For("news"))
+
+	w = putLevel(router, `{"package": "news", "level": "debug"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelDebug, levels.For("news"))
+
+	w = putLevel(router, `{"package": "news"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, slog.LevelError, levels.For("news"))
+}
+
+func TestSetLevelsInvalidInput(t *testing.T) {
+	router := setupAdminRouter(NewLevels(slog.LevelInfo, nil))
+
+	for _, body := range []string{`{"level": "loud"}`, `{}`, `not json`} {
+		w := putLevel(router, body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+}
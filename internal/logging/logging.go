@@ -0,0 +1,108 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Header used to receive and return the ID of each request
+const RequestIDHeader = "X-Request-ID"
+
+// Longest request ID accepted from clients, longer ones are replaced
+const maxRequestIDLength = 128
+
+type contextKey struct{}
+
+// NewSlogHandler builds the slog handler writing to w with the given format (text or json)
+func NewSlogHandler(format string, w io.Writer, opts *slog.HandlerOptions) (slog.Handler, error) {
+	switch format {
+	case "", "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+}
+
+// WithLogger returns a copy of ctx carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the request-scoped logger carried by ctx, or the default one
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// With returns a copy of ctx whose logger adds attrs to every line
+func With(ctx context.Context, attrs ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(attrs...))
+}
+
+// Middleware assigns every request an ID, reusing the one sent by the client in
+// the X-Request-ID header when it is valid, returns it in the response and
+// stores a logger tagged with it in the request context. It replaces the Gin
+// access log with a line written by that logger once the request is served.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+
+		attrs := []any{
+			slog.String("request_id", requestID),
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+		}
+
+		// Link the log lines with the trace of the request, if it is being traced
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			attrs = append(attrs, slog.String("trace_id", spanContext.TraceID().String()))
+		}
+
+		c.Request = c.Request.WithContext(With(c.Request.Context(), attrs...))
+		c.Next()
+
+		// Later middlewares may have tagged the logger with the user, so it is taken again
+		FromContext(c.Request.Context()).Info("request completed",
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+		)
+	}
+}
+
+// Request IDs sent by clients end up in the logs, so only short printable ASCII IDs are accepted
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Send the default logger to a buffer with the JSON format for the duration of the test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	handler, err := NewSlogHandler("json", &buf, nil)
+	require.NoError(t, err)
+
+	previous := slog.Default()
+	slog.SetDefault(slog.New(handler))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	var lines []map[string]any
+	for _, raw := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var line map[string]any
+		require.NoError(t, json.Unmarshal([]byte(raw), &line))
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func setupRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/news/:id", handler)
+	return router
+}
+
+func TestMiddlewareGeneratesRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	router := setupRouter(func(c *gin.Context) {
+		FromContext(c.Request.Context()).Info("article request received")
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+	router.ServeHTTP(w, req)
+
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.Len(t, requestID, 32)
+
+	lines := decodeLines(t, buf)
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Equal(t, requestID, line["request_id"])
+		assert.Equal(t, "/news/:id", line["route"])
+	}
+	assert.Equal(t, "request completed", lines[1]["msg"])
+	assert.Equal(t, float64(http.StatusOK), lines[1]["status"])
+}
+
+func TestMiddlewareReusesClientRequestID(t *testing.T) {
+	buf := captureLogs(t)
+	router := setupRouter(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+	req.Header.Set(RequestIDHeader, "abc-123")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc-123", w.Header().Get(RequestIDHeader))
+	assert.Equal(t, "abc-123", decodeLines(t, buf)[0]["request_id"])
+}
+
+func TestMiddlewareRejectsInvalidRequestID(t *testing.T) {
+	captureLogs(t)
+	router := setupRouter(func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	for _, id := range []string{"has spaces", strings.Repeat("a", maxRequestIDLength+1), "line\nbreak"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+		req.Header[RequestIDHeader] = []string{id}
+		router.ServeHTTP(w, req)
+
+		assert.NotEqual(t, id, w.Header().Get(RequestIDHeader))
+		assert.Len(t, w.Header().Get(RequestIDHeader), 32)
+	}
+}
+
+func TestMiddlewareLogsAttrsAddedLater(t *testing.T) {
+	buf := captureLogs(t)
+	router := setupRouter(func(c *gin.Context) {
+		c.Request = c.Request.WithContext(With(c.Request.Context(), slog.String("user", "alice")))
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, "alice", decodeLines(t, buf)[0]["user"])
+}
+
+func TestFromContextDefaultsToDefaultLogger(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+}
+
+func TestNewSlogHandlerUnknownFormat(t *testing.T) {
+	_, err := NewSlogHandler("xml", &bytes.Buffer{}, nil)
+
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Middleware())
+	RegisterRoutes(r.Group(""))
+	r.GET("/news/:id", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestMiddlewareLabelsByRoute(t *testing.T) {
+	router := setupRouter()
+	before := testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, "/news/:id", "200"))
+
+	for _, path := range []string{"/news/1", "/news/2"} {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+	}
+
+	after := testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, "/news/:id", "200"))
+	assert.Equal(t, float64(2), after-before)
+}
+
+func TestMiddlewareUnmatchedRoute(t *testing.T) {
+	router := setupRouter()
+	before := testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/does/not/exist", nil)
+	router.ServeHTTP(w, req)
+
+	after := testutil.ToFloat64(httpRequestsTotal.WithLabelValues(http.MethodGet, unmatchedRoute, "404"))
+	assert.Equal(t, float64(1), after-before)
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router := setupRouter()
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+	router.ServeHTTP(w, req)
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/metrics", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, strings.Contains(w.Body.String(), `http_requests_total{method="GET",route="/news/:id",status="200"}`))
+	assert.Contains(t, w.Body.String(), "http_request_duration_seconds_bucket")
+}
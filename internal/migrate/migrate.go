@@ -0,0 +1,295 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoMigrations is used when there is no applied migration to roll back.
+var ErrNoMigrations = errors.New("no applied migrations")
+
+// ErrIrreversible is used when rolling back a migration without a down file.
+var ErrIrreversible = errors.New("migration has no down file")
+
+// ErrUnknownVersion is used when baselining at a version without a migration.
+var ErrUnknownVersion = errors.New("unknown migration version")
+
+// Key of the advisory lock held while migrating, so replicas starting at the same time do not race
+const lockKey = 7283541650
+
+// Migration files are named <version>_<name>.sql, with an optional <version>_<name>.down.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(\.down)?\.sql$`)
+
+// Names accepted by Create
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status of a migration, AppliedAt is nil while it is pending
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// Constructor, migrations are read from fsys
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Load reads the migrations of fsys sorted by version
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %q: %w", entry.Name(), err)
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicated migration version %d: %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in a single transaction and returns them
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.inLockedTx(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+
+			slog.Info("applying migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);", migration.Version, migration.Name); err != nil {
+				return err
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("migrations applied", slog.Int("count", len(applied)))
+	return applied, nil
+}
+
+// Down rolls back the last applied migration and returns it
+func (m *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var rolledBack *Migration
+	err := m.inLockedTx(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		// Find the applied migration with the highest version
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := done[m.migrations[i].Version]; ok {
+				rolledBack = &m.migrations[i]
+				break
+			}
+		}
+		if rolledBack == nil {
+			return ErrNoMigrations
+		}
+		if strings.TrimSpace(rolledBack.Down) == "" {
+			return fmt.Errorf("%w: %d_%s", ErrIrreversible, rolledBack.Version, rolledBack.Name)
+		}
+
+		slog.Info("rolling back migration", slog.Int64("version", rolledBack.Version), slog.String("name", rolledBack.Name))
+		if _, err := tx.ExecContext(ctx, rolledBack.Down); err != nil {
+			return fmt.Errorf("rollback of %d_%s failed: %w", rolledBack.Version, rolledBack.Name, err)
+		}
+		_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version=$1;", rolledBack.Version)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rolledBack, nil
+}
+
+// Baseline marks every migration up to version as applied without running it and
+// returns them. It is meant for databases whose schema was created before
+// migrations were tracked.
+func (m *Migrator) Baseline(ctx context.Context, version int64) ([]Migration, error) {
+	if !slices.ContainsFunc(m.migrations, func(migration Migration) bool { return migration.Version == version }) {
+		return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+
+	var marked []Migration
+	err := m.inLockedTx(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			if _, ok := done[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+
+			slog.Info("marking migration as applied", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+			if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2);", migration.Version, migration.Name); err != nil {
+				return err
+			}
+			marked = append(marked, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marked, nil
+}
+
+// Status lists every migration and when it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	statuses := make([]Status, 0, len(m.migrations))
+	err := m.inLockedTx(ctx, func(tx *sql.Tx, done map[int64]time.Time) error {
+		for _, migration := range m.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+// Run fn in a transaction holding the migration lock, with the versions already applied
+func (m *Migrator) inLockedTx(ctx context.Context, fn func(tx *sql.Tx, done map[int64]time.Time) error) error {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// The lock is released when the transaction ends
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1);", lockKey); err != nil {
+		return err
+	}
+
+	const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);`
+	if _, err := tx.ExecContext(ctx, createTable); err != nil {
+		return err
+	}
+
+	done, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx, done); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func appliedVersions(ctx context.Context, tx *sql.Tx) (map[int64]time.Time, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	done := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// Create writes empty up and down files for a new migration in dir, numbered
+// after the last one, and returns their paths
+func Create(dir string, name string) (string, string, error) {
+	if !migrationName.MatchString(name) {
+		return "", "", fmt.Errorf("invalid migration name %q: must only have lowercase letters, digits and underscores", name)
+	}
+
+	migrations, err := Load(os.DirFS(dir))
+	if err != nil {
+		return "", "", err
+	}
+	var version int64 = 1
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%03d_%s", version, name))
+	up, down := base+".sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Revert "+name+"\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	return up, down, nil
+}
@@ -0,0 +1,262 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFS = fstest.MapFS{
+	"001_init.sql":       {Data: []byte("CREATE TABLE a (id INT);")},
+	"001_init.down.sql":  {Data: []byte("DROP TABLE a;")},
+	"002_more.sql":       {Data: []byte("CREATE TABLE b (id INT);")},
+	"002_more.down.sql":  {Data: []byte("DROP TABLE b;")},
+	"003_no_down.sql":    {Data: []byte("CREATE TABLE c (id INT);")},
+	"README.md":          {Data: []byte("not a migration")},
+	"embed.go":           {Data: []byte("package migrations")},
+	"010_skipped.txt":    {Data: []byte("not a migration")},
+	"subdir/004_foo.sql": {Data: []byte("ignored")},
+}
+
+func expectLockedTx(mock sqlmock.Sqlmock, applied *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(\\$1\\)").
+		WithArgs(lockKey).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, applied_at FROM schema_migrations").
+		WillReturnRows(applied)
+}
+
+func TestLoadSortsMigrations(t *testing.T) {
+	migrations, err := Load(testFS)
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+	assert.Equal(t, Migration{Version: 1, Name: "init", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"}, migrations[0])
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "no_down", migrations[2].Name)
+	assert.Empty(t, migrations[2].Down)
+}
+
+func TestLoadInvalidMigrations(t *testing.T) {
+	_, err := Load(fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE a;")}})
+	assert.ErrorContains(t, err, "has no up file")
+
+	_, err = Load(fstest.MapFS{
+		"001_init.sql":  {Data: []byte("CREATE TABLE a (id INT);")},
+		"001_other.sql": {Data: []byte("CREATE TABLE b (id INT);")},
+	})
+	assert.ErrorContains(t, err, "duplicated migration version 1")
+}
+
+func TestUpAppliesPendingMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(int64(1), time.Now()))
+	mock.ExpectExec("CREATE TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(2), "more").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("CREATE TABLE c").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(3), "no_down").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, int64(2), applied[0].Version)
+	assert.Equal(t, int64(3), applied[1].Version)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpRollsBackOnFailure(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectExec("CREATE TABLE a").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+
+	applied, err := migrator.Up(context.Background())
+
+	assert.ErrorContains(t, err, "migration 1_init failed: syntax error")
+	assert.Nil(t, applied)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownRollsBackLastMigration(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}).
+		AddRow(int64(1), time.Now()).
+		AddRow(int64(2), time.Now()))
+	mock.ExpectExec("DROP TABLE b").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM schema_migrations WHERE version=\\$1").
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	migration, err := migrator.Down(context.Background())
+
+	assert.NoError(t, err)
+	require.NotNil(t, migration)
+	assert.Equal(t, "more", migration.Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownWithoutDownFile(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(int64(3), time.Now()))
+	mock.ExpectRollback()
+
+	_, err = migrator.Down(context.Background())
+
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDownWithoutAppliedMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectRollback()
+
+	_, err = migrator.Down(context.Background())
+
+	assert.ErrorIs(t, err, ErrNoMigrations)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBaselineMarksMigrationsAsApplied(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	// Nothing is executed, only recorded
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(1), "init").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations").
+		WithArgs(int64(2), "more").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	marked, err := migrator.Baseline(context.Background(), 2)
+
+	assert.NoError(t, err)
+	require.Len(t, marked, 2)
+	assert.Equal(t, "init", marked[0].Name)
+	assert.Equal(t, "more", marked[1].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBaselineUnknownVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	_, err = migrator.Baseline(context.Background(), 7)
+
+	assert.ErrorIs(t, err, ErrUnknownVersion)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	migrator, err := New(db, testFS)
+	require.NoError(t, err)
+
+	appliedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	expectLockedTx(mock, sqlmock.NewRows([]string{"version", "applied_at"}).AddRow(int64(1), appliedAt))
+	mock.ExpectCommit()
+
+	statuses, err := migrator.Status(context.Background())
+
+	assert.NoError(t, err)
+	require.Len(t, statuses, 3)
+	require.NotNil(t, statuses[0].AppliedAt)
+	assert.Equal(t, appliedAt, *statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	assert.Nil(t, statuses[2].AppliedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "004_news_search.sql"), []byte("SELECT 1;"), 0o644))
+
+	up, down, err := Create(dir, "add_authors")
+
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "005_add_authors.sql"), up)
+	assert.Equal(t, filepath.Join(dir, "005_add_authors.down.sql"), down)
+	assert.FileExists(t, up)
+	assert.FileExists(t, down)
+
+	_, _, err = Create(dir, "Add Authors")
+	assert.ErrorContains(t, err, "invalid migration name")
+}
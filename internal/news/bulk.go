@@ -0,0 +1,299 @@
+package news
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Formats of the bulk import and export files
+const (
+	FormatJSONL = "jsonl"
+	FormatCSV   = "csv"
+)
+
+const (
+	// DefaultImportBatchSize is the number of articles written in each transaction
+	DefaultImportBatchSize = 500
+	// MaxReportedErrors caps the invalid records listed in an import report
+	MaxReportedErrors = 100
+	// MaxExternalIDLength is the maximum number of bytes allowed in an external id
+	MaxExternalIDLength = 200
+)
+
+// Maximum size of a JSON line, the body may double in size once escaped
+const maxRecordLength = 2*MaxBodyLength + 4*1024
+
+// ErrUnsupportedFormat is used when asking for a bulk format other than jsonl or csv.
+var ErrUnsupportedFormat = errors.New("unsupported format")
+
+// ErrInvalidImportFile is used when an import file cannot be read any further, e.g. a CSV file without the required columns.
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+// ErrMalformedRecord is used when a line of an import file cannot be decoded, the next lines can still be read.
+var ErrMalformedRecord = errors.New("malformed record")
+
+// Columns of the CSV files, external_id and title are required on import. Tags are separated by commas.
+var csvColumns = []string{"id", "external_id", "title", "body", "datetime", "category", "tags", "status", "publish_at", "author_id", "author_name"}
+
+// Record is an article in a bulk import or export file. The ID is only
+// exported, imports are matched by ExternalID. A zero Datetime keeps the date
+// of existing articles and uses the current time for new ones. Like in the
+// API, records without a status are published, or scheduled when they have a
+// PublishAt, and the tags replace the previous ones. Articles keep their
+// author when the record has none.
+type Record struct {
+	ID         uint64     `json:"id,omitempty"`
+	ExternalID string     `json:"external_id"`
+	Title      string     `json:"title"`
+	Body       string     `json:"body"`
+	Datetime   time.Time  `json:"datetime,omitzero"`
+	Category   string     `json:"category,omitempty"`
+	Tags       []string   `json:"tags,omitempty"`
+	Status     string     `json:"status,omitempty"`
+	PublishAt  *time.Time `json:"publish_at,omitempty"`
+	AuthorID   string     `json:"author_id,omitempty"`
+	AuthorName string     `json:"author_name,omitempty"`
+}
+
+// RecordReader reads the records of an import file one at a time, Read
+// returns the line of the record and io.EOF at the end of the file
+type RecordReader interface {
+	Read() (Record, int, error)
+}
+
+// RecordWriter writes the records of an export file, Flush must be called at the end
+type RecordWriter interface {
+	Write(record Record) error
+	Flush() error
+}
+
+// Options received when importing articles
+type ImportOptions struct {
+	DryRun    bool
+	BatchSize int
+}
+
+// ImportReport summarizes an import, invalid records are skipped and listed in Errors
+type ImportReport struct {
+	DryRun    bool        `json:"dry_run"`
+	Processed int         `json:"processed"`
+	Valid     int         `json:"valid"`
+	Inserted  int         `json:"inserted"`
+	Updated   int         `json:"updated"`
+	Invalid   int         `json:"invalid"`
+	Errors    []LineError `json:"errors"`
+}
+
+// LineError is the reason why the record of a line was not imported
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+func (r *ImportReport) addError(line int, err error) {
+	r.Invalid++
+	if len(r.Errors) < MaxReportedErrors {
+		r.Errors = append(r.Errors, LineError{Line: line, Error: err.Error()})
+	}
+}
+
+// NewRecordReader reads records from r in the given format
+func NewRecordReader(format string, r io.Reader) (RecordReader, error) {
+	switch format {
+	case FormatJSONL:
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxRecordLength)
+		return &jsonlReader{scanner: scanner}, nil
+	case FormatCSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.ReuseRecord = true
+		return &csvReader{reader: reader}, nil
+	default:
+		return nil, fmt.Errorf("%w %q: must be %s or %s", ErrUnsupportedFormat, format, FormatJSONL, FormatCSV)
+	}
+}
+
+// NewRecordWriter writes records to w in the given format
+func NewRecordWriter(format string, w io.Writer) (RecordWriter, error) {
+	switch format {
+	case FormatJSONL:
+		return &jsonlWriter{encoder: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		writer := csv.NewWriter(w)
+		// The header is flushed with the first records
+		if err := writer.Write(csvColumns); err != nil {
+			return nil, err
+		}
+		return &csvWriter{writer: writer}, nil
+	default:
+		return nil, fmt.Errorf("%w %q: must be %s or %s", ErrUnsupportedFormat, format, FormatJSONL, FormatCSV)
+	}
+}
+
+type jsonlReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *jsonlReader) Read() (Record, int, error) {
+	// Every line is decoded on its own, so a malformed line does not stop the import
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return Record{}, r.line, fmt.Errorf("%w: %v", ErrMalformedRecord, err)
+		}
+		return record, r.line, nil
+	}
+	if errors.Is(r.scanner.Err(), bufio.ErrTooLong) {
+		return Record{}, r.line + 1, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImportFile, r.line+1, maxRecordLength)
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Record{}, r.line + 1, err
+	}
+	return Record{}, r.line, io.EOF
+}
+
+type csvReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func (r *csvReader) Read() (Record, int, error) {
+	// The first row has the column names
+	if r.columns == nil {
+		header, err := r.reader.Read()
+		if err != nil {
+			return Record{}, 1, err
+		}
+		r.columns = make(map[string]int, len(header))
+		for i, name := range header {
+			r.columns[name] = i
+		}
+		for _, name := range []string{"external_id", "title"} {
+			if _, ok := r.columns[name]; !ok {
+				return Record{}, 1, fmt.Errorf("%w: missing CSV column %q", ErrInvalidImportFile, name)
+			}
+		}
+	}
+
+	fields, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return Record{}, parseErr.StartLine, fmt.Errorf("%w: %v", ErrMalformedRecord, parseErr.Err)
+		}
+		return Record{}, 0, err
+	}
+	line, _ := r.reader.FieldPos(0)
+
+	field := func(name string) string {
+		i, ok := r.columns[name]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return fields[i]
+	}
+
+	record := Record{
+		ExternalID: field("external_id"),
+		Title:      field("title"),
+		Body:       field("body"),
+		Category:   field("category"),
+		Status:     field("status"),
+		AuthorID:   field("author_id"),
+		AuthorName: field("author_name"),
+	}
+	if value := field("datetime"); value != "" {
+		if record.Datetime, err = time.Parse(time.RFC3339, value); err != nil {
+			return Record{}, line, fmt.Errorf("%w: datetime must be an RFC 3339 date", ErrMalformedRecord)
+		}
+	}
+	if value := field("publish_at"); value != "" {
+		publishAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return Record{}, line, fmt.Errorf("%w: publish_at must be an RFC 3339 date", ErrMalformedRecord)
+		}
+		record.PublishAt = &publishAt
+	}
+	if value := field("tags"); value != "" {
+		record.Tags = strings.Split(value, ",")
+	}
+	return record, line, nil
+}
+
+type jsonlWriter struct {
+	encoder *json.Encoder
+}
+
+func (w *jsonlWriter) Write(record Record) error {
+	return w.encoder.Encode(record)
+}
+
+func (w *jsonlWriter) Flush() error {
+	return nil
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+func (w *csvWriter) Write(record Record) error {
+	var publishAt string
+	if record.PublishAt != nil {
+		publishAt = record.PublishAt.Format(time.RFC3339)
+	}
+	return w.writer.Write([]string{
+		strconv.FormatUint(record.ID, 10),
+		record.ExternalID,
+		record.Title,
+		record.Body,
+		record.Datetime.Format(time.RFC3339),
+		record.Category,
+		strings.Join(record.Tags, ","),
+		record.Status,
+		publishAt,
+		record.AuthorID,
+		record.AuthorName,
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// Normalize and validate an imported record
+func validateRecord(record *Record) error {
+	input := ArticleInput{Title: record.Title, Body: record.Body, Category: record.Category, Tags: record.Tags, Status: record.Status, PublishAt: record.PublishAt}
+	if err := validateArticle(&input); err != nil {
+		return err
+	}
+	record.Title = input.Title
+	record.Category = input.Category
+	record.Tags = input.Tags
+	record.Status = input.Status
+	record.PublishAt = input.PublishAt
+
+	if record.ExternalID == "" {
+		return fmt.Errorf("%w: external_id is required", ErrInvalidArticle)
+	}
+	if len(record.ExternalID) > MaxExternalIDLength {
+		return fmt.Errorf("%w: external_id must be at most %d bytes", ErrInvalidArticle, MaxExternalIDLength)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package news
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVReader(t *testing.T) {
+	input := "title,external_id,datetime,extra\n" +
+		"First,wp-1,2024-05-01T10:00:00Z,x\n" +
+		"\"Multi\nline\",wp-2,,\n" +
+		"Bad date,wp-3,yesterday,\n"
+	reader, err := NewRecordReader(FormatCSV, strings.NewReader(input))
+	require.NoError(t, err)
+
+	record, line, err := reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, line)
+	assert.Equal(t, Record{ExternalID: "wp-1", Title: "First", Datetime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}, record)
+
+	record, line, err = reader.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, line)
+	assert.Equal(t, "Multi\nline", record.Title)
+
+	_, line, err = reader.Read()
+	assert.ErrorIs(t, err, ErrMalformedRecord)
+	assert.Equal(t, 5, line)
+
+	_, _, err = reader.Read()
+	assert.True(t, errors.Is(err, io.EOF))
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	publishAt := time.Date(2026, 2, 10, 17, 0, 0, 0, time.UTC)
+	records := []Record{
+		{ID: 1, ExternalID: "wp-1", Title: "First", Body: "Body", Datetime: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), Status: StatusPublished},
+		{ID: 2, ExternalID: "wp-2", Title: "Second", Datetime: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC), Category: "tech",
+			Tags: []string{"go", "security"}, Status: StatusScheduled, PublishAt: &publishAt, AuthorID: "64b7f0c2a1b2c3d4e5f60718", AuthorName: "alice"},
+	}
+
+	var buf strings.Builder
+	writer, err := NewRecordWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+	for _, record := range records {
+		require.NoError(t, writer.Write(record))
+	}
+	require.NoError(t, writer.Flush())
+
+	reader, err := NewRecordReader(FormatCSV, strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	for _, expected := range records {
+		record, _, err := reader.Read()
+		require.NoError(t, err)
+		// IDs are only exported
+		expected.ID = 0
+		assert.Equal(t, expected, record)
+	}
+}
+
+func TestCSVReaderMissingColumn(t *testing.T) {
+	reader, err := NewRecordReader(FormatCSV, strings.NewReader("title,body\nFirst,Body\n"))
+	require.NoError(t, err)
+
+	_, _, err = reader.Read()
+
+	assert.ErrorIs(t, err, ErrInvalidImportFile)
+}
+
+func TestJSONLReaderLineTooLong(t *testing.T) {
+	reader, err := NewRecordReader(FormatJSONL, strings.NewReader(strings.Repeat("a", maxRecordLength+1)))
+	require.NoError(t, err)
+
+	_, _, err = reader.Read()
+
+	assert.ErrorIs(t, err, ErrInvalidImportFile)
+}
+
+func TestUnsupportedFormat(t *testing.T) {
+	_, err := NewRecordReader("xml", strings.NewReader(""))
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = NewRecordWriter("xml", io.Discard)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
+
+func TestValidateRecord(t *testing.T) {
+	record := Record{ExternalID: "wp-1", Title: "  Title  ", Tags: []string{"Go", "go "}}
+	assert.NoError(t, validateRecord(&record))
+	assert.Equal(t, "Title", record.Title)
+	assert.Equal(t, []string{"go"}, record.Tags)
+	assert.Equal(t, StatusPublished, record.Status)
+
+	// Drafts stay drafts
+	record = Record{ExternalID: "wp-1", Title: "Title", Status: StatusDraft}
+	assert.NoError(t, validateRecord(&record))
+	assert.Equal(t, StatusDraft, record.Status)
+
+	record = Record{ExternalID: "wp-1", Title: "Title", Status: StatusScheduled}
+	assert.ErrorIs(t, validateRecord(&record), ErrInvalidArticle)
+
+	record = Record{ExternalID: strings.Repeat("x", MaxExternalIDLength+1), Title: "Title"}
+	assert.ErrorIs(t, validateRecord(&record), ErrInvalidArticle)
+}
+
+func TestCSVRoundTripTagWithComma(t *testing.T) {
+	// A JSONL record can hold a tag with a comma, which a CSV export would split in two
+	reader, err := NewRecordReader(FormatJSONL, strings.NewReader(`{"external_id":"wp-1","title":"First","tags":["go,security"]}`+"\n"))
+	require.NoError(t, err)
+	record, _, err := reader.Read()
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, validateRecord(&record), ErrInvalidArticle)
+
+	// Valid tags come back unchanged
+	record.Tags = []string{"go", "security"}
+	require.NoError(t, validateRecord(&record))
+	var buf strings.Builder
+	writer, err := NewRecordWriter(FormatCSV, &buf)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write(record))
+	require.NoError(t, writer.Flush())
+
+	reader, err = NewRecordReader(FormatCSV, strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	imported, _, err := reader.Read()
+	require.NoError(t, err)
+	assert.Equal(t, record.Tags, imported.Tags)
+}
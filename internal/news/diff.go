@@ -0,0 +1,175 @@
+package news
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Granularities of the revision diffs
+const (
+	DiffLines = "line"
+	DiffWords = "word"
+)
+
+// Operations of a diff
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// Texts with more changes than this are shown as entirely replaced, it bounds
+// the memory used by the diff to a few megabytes
+const maxDiffEdits = 1000
+
+// DiffOp is a piece of text kept, inserted or deleted between two revisions
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff the lines or the words of two texts, consecutive operations of the same kind are merged.
+// Joining the texts of the equal and deleted operations gives back a, and of the equal and inserted ones b.
+func diffText(a string, b string, granularity string) []DiffOp {
+	split := splitLines
+	if granularity == DiffWords {
+		split = splitWords
+	}
+	return diffTokens(split(a), split(b))
+}
+
+// Split after every newline, so joining the lines gives back the text
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.SplitAfter(s, "\n")
+}
+
+// Split into runs of spaces and runs of anything else, so joining the words gives back the text
+func splitWords(s string) []string {
+	var words []string
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			words = append(words, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		words = append(words, s[start:])
+	}
+	return words
+}
+
+func diffTokens(a []string, b []string) []DiffOp {
+	var ops []DiffOp
+	add := func(op string, text string) {
+		if text == "" {
+			return
+		}
+		if n := len(ops); n > 0 && ops[n-1].Op == op {
+			ops[n-1].Text += text
+			return
+		}
+		ops = append(ops, DiffOp{Op: op, Text: text})
+	}
+
+	// The common prefix and suffix are kept as they are
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	add(DiffEqual, strings.Join(a[:prefix], ""))
+	for _, op := range myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]) {
+		add(op.Op, op.Text)
+	}
+	add(DiffEqual, strings.Join(a[len(a)-suffix:], ""))
+	return ops
+}
+
+// Shortest edit script between a and b with Myers' algorithm, see "An O(ND)
+// Difference Algorithm and Its Variations". The V array of every step is kept
+// to walk back the path once the end is reached.
+func myers(a []string, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxDiffEdits)
+
+	// v[offset+k] is the furthest x reached on diagonal k
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	// Too many changes, replace everything
+	return []DiffOp{{Op: DiffDelete, Text: strings.Join(a, "")}, {Op: DiffInsert, Text: strings.Join(b, "")}}
+}
+
+// Walk back the path found by myers, trace[d] holds v[-d-1..d+1] before step d
+func backtrack(a []string, b []string, trace [][]int) []DiffOp {
+	var ops []DiffOp
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, DiffOp{Op: DiffEqual, Text: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				ops = append(ops, DiffOp{Op: DiffInsert, Text: b[prevY]})
+			} else {
+				ops = append(ops, DiffOp{Op: DiffDelete, Text: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	// The path was walked from the end
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
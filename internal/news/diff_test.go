@@ -0,0 +1,89 @@
+package news
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Rebuild both texts from the operations of a diff
+func applyDiff(ops []DiffOp) (string, string) {
+	var a, b strings.Builder
+	for _, op := range ops {
+		if op.Op != DiffInsert {
+			a.WriteString(op.Text)
+		}
+		if op.Op != DiffDelete {
+			b.WriteString(op.Text)
+		}
+	}
+	return a.String(), b.String()
+}
+
+func TestDiffTextLines(t *testing.T) {
+	a := "uno\ndos\ntres\ncuatro\n"
+	b := "uno\ntres\ncuatro\ncinco\n"
+
+	ops := diffText(a, b, DiffLines)
+
+	assert.Equal(t, []DiffOp{
+		{DiffEqual, "uno\n"},
+		{DiffDelete, "dos\n"},
+		{DiffEqual, "tres\ncuatro\n"},
+		{DiffInsert, "cinco\n"},
+	}, ops)
+}
+
+func TestDiffTextWords(t *testing.T) {
+	ops := diffText("La policía alertó  de un fraude", "La policía alertó de una estafa", DiffWords)
+
+	assert.Equal(t, []DiffOp{
+		{DiffEqual, "La policía alertó"},
+		{DiffDelete, "  "},
+		{DiffInsert, " "},
+		{DiffEqual, "de "},
+		{DiffDelete, "un"},
+		{DiffInsert, "una"},
+		{DiffEqual, " "},
+		{DiffDelete, "fraude"},
+		{DiffInsert, "estafa"},
+	}, ops)
+}
+
+func TestDiffTextEdgeCases(t *testing.T) {
+	assert.Nil(t, diffText("", "", DiffLines))
+	assert.Equal(t, []DiffOp{{DiffInsert, "new"}}, diffText("", "new", DiffWords))
+	assert.Equal(t, []DiffOp{{DiffDelete, "old\n"}}, diffText("old\n", "", DiffLines))
+	assert.Equal(t, []DiffOp{{DiffEqual, "same\n"}}, diffText("same\n", "same\n", DiffLines))
+}
+
+func TestDiffTextRebuildsTexts(t *testing.T) {
+	cases := [][2]string{
+		{"a b c d e f", "a c d x f g"},
+		{"first\nsecond\nthird", "second\nthird\nfourth"},
+		{"x\ny\nx\ny\n", "y\nx\ny\nx\n"},
+		{"no newline at the end", "no newline\nat the end\n"},
+	}
+
+	for _, tc := range cases {
+		for _, granularity := range []string{DiffLines, DiffWords} {
+			a, b := applyDiff(diffText(tc[0], tc[1], granularity))
+			assert.Equal(t, tc[0], a, granularity)
+			assert.Equal(t, tc[1], b, granularity)
+		}
+	}
+}
+
+func TestDiffTextTooManyChanges(t *testing.T) {
+	var a, b strings.Builder
+	for i := range maxDiffEdits {
+		fmt.Fprintf(&a, "old %d\n", i)
+		fmt.Fprintf(&b, "new %d\n", i)
+	}
+
+	ops := diffText(a.String(), b.String(), DiffLines)
+
+	assert.Equal(t, []DiffOp{{DiffDelete, a.String()}, {DiffInsert, b.String()}}, ops)
+}
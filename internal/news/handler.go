@@ -1,10 +1,13 @@
 package news
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 	"strconv"
 
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/user"
 	"github.com/gin-gonic/gin"
 )
 
@@ -16,13 +19,45 @@ func NewHandler(svc Service) *Handler {
 	return &Handler{svc: svc}
 }
 
-func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
+// Maximum size of the JSON documents accepted by the editor API
+const maxRequestBodyBytes = MaxBodyLength + 4*1024
+
+// Maximum size of the files accepted by the bulk import, they are streamed so it only bounds the request duration
+const maxImportBodyBytes = 512 << 20
+
+// RegisterRoutes registers the public read routes and the editor routes, the
+// latter are guarded by the given middlewares (e.g. authentication).
+func RegisterRoutes(rg *gin.RouterGroup, h *Handler, editorMiddlewares ...gin.HandlerFunc) {
 	grp := rg.Group("/news")
 
 	grp.GET("", h.getNews)
+	grp.GET("/list", h.listNews)
+	grp.GET("/search", h.searchNews)
+	// gin needs the wildcard to be named like the one of the revision routes, here it holds the slug
+	grp.GET("/:id", h.getNewsBySlug)
+	rg.GET("/authors/:id/news", h.listNews)
+	rg.GET("/tags", h.listTags)
+	rg.GET("/categories", h.listCategories)
+
+	editor := grp.Group("", editorMiddlewares...)
+	editor.POST("", h.createNews)
+	editor.PUT("/:id", h.updateNews)
+	editor.PATCH("/:id", h.patchNews)
+	editor.DELETE("/:id", h.deleteNews)
+	editor.GET("/:id/revisions", h.listRevisions)
+	editor.GET("/:id/revisions/:revision", h.getRevision)
+	editor.POST("/:id/revisions/:revision/restore", h.restoreRevision)
+	editor.GET("/:id/diff", h.diffRevisions)
 	slog.Info("news routes registered")
 }
 
+// RegisterAdminRoutes registers the bulk import and export routes, they must be restricted to admins
+func RegisterAdminRoutes(rg *gin.RouterGroup, h *Handler) {
+	rg.POST("/news/import", h.importNews)
+	rg.GET("/news/export", h.exportNews)
+	slog.Info("news admin routes registered")
+}
+
 func validateAndParseID(idStr string) (uint64, error) {
 	id, err := strconv.ParseUint(idStr, 10, 64)
 	if err != nil {
@@ -33,37 +68,634 @@ func validateAndParseID(idStr string) (uint64, error) {
 }
 
 func (h *Handler) getNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
 	idStr := c.Query("id")
 	clientIP := c.ClientIP()
-	slog.Debug("article request received", slog.String("id", idStr), slog.String("client_ip", clientIP))
+	logger.Debug("article request received", slog.String("id", idStr), slog.String("client_ip", clientIP))
+
+	// Without an ID, a tag or category filter lists the matching articles
+	if idStr == "" && (c.Query("tag") != "" || c.Query("category") != "") {
+		h.listNews(c)
+		return
+	}
 
 	// If the ID is empty, return a bad request error
 	if idStr == "" {
-		slog.Warn("invalid request: missing id parameter", slog.String("client_ip", clientIP))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id parameter is required"})
+		logger.Warn("invalid request: missing id parameter", slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeMissingID, "id parameter is required")
 		return
 	}
 
 	// If the ID is not a valid unsigned int, return a bad request error
 	id, err := validateAndParseID(idStr)
 	if err != nil {
-		slog.Warn("invalid request: id must be a valid number", slog.String("id", idStr), slog.String("client_ip", clientIP))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "id must be a valid number"})
+		logger.Warn("invalid request: id must be a valid number", slog.String("id", idStr), slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
 		return
 	}
 
-	// Get the article by ID from the service and handle any errors
-	article, err := h.svc.GetByID(c.Request.Context(), id)
+	preview, ok := previewMode(c)
+	if !ok {
+		return
+	}
+
+	// Get the article by ID from the service and handle any errors, editors can preview unpublished articles
+	var article *Article
+	if preview {
+		article, err = h.svc.Preview(c.Request.Context(), id)
+	} else {
+		article, err = h.svc.GetByID(c.Request.Context(), id)
+	}
 
 	//If there was an error getting the article, return not found error
 	if err != nil {
 		// Log the actual error for debugging
-		slog.Error("error fetching article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
-		c.JSON(http.StatusNotFound, gin.H{"error": "article not found"})
+		logger.Error("error fetching article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusNotFound, codeArticleNotFound, "article not found")
+		return
+	}
+
+	// Articles are served at their slug, the id form is kept for old links
+	if article.Slug != "" {
+		logger.Info("article moved to its slug", slog.Uint64("id", id), slog.String("slug", article.Slug), slog.String("client_ip", clientIP))
+		redirectToArticle(c, article)
+		return
+	}
+
+	// Return the article rendered as JSON or HTML depending on the client preferences
+	logger.Info("article request successful", slog.Uint64("id", id), slog.String("client_ip", clientIP))
+	format := render(c, http.StatusOK, "article.html", article, article.ToOutput())
+	articlesServed.WithLabelValues(format).Inc()
+}
+
+func (h *Handler) getNewsBySlug(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	slug := c.Param("id")
+	clientIP := c.ClientIP()
+	logger.Debug("article request received", slog.String("slug", slug), slog.String("client_ip", clientIP))
+
+	preview, ok := previewMode(c)
+	if !ok {
+		return
+	}
+
+	// Get the article by slug from the service, editors can preview unpublished articles
+	var article *Article
+	var err error
+	if preview {
+		article, err = h.svc.PreviewBySlug(c.Request.Context(), slug)
+	} else {
+		article, err = h.svc.GetBySlug(c.Request.Context(), slug)
+	}
+	if err != nil {
+		logger.Error("error fetching article", slog.String("slug", slug), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusNotFound, codeArticleNotFound, "article not found")
+		return
+	}
+
+	// The slug changed with the title, send the client to the current one
+	if article.Slug != slug {
+		logger.Info("article moved to a new slug", slog.String("slug", slug), slog.String("new_slug", article.Slug), slog.String("client_ip", clientIP))
+		redirectToArticle(c, article)
+		return
+	}
+
+	// Return the article rendered as JSON or HTML depending on the client preferences
+	logger.Info("article request successful", slog.Uint64("id", article.ID), slog.String("slug", slug), slog.String("client_ip", clientIP))
+	format := render(c, http.StatusOK, "article.html", article, article.ToOutput())
+	articlesServed.WithLabelValues(format).Inc()
+}
+
+// Permanently redirect to the page of the article, keeping the query parameters
+// other than the id (e.g. preview or format)
+func redirectToArticle(c *gin.Context, article *Article) {
+	query := c.Request.URL.Query()
+	query.Del("id")
+
+	location := article.URL()
+	if encoded := query.Encode(); encoded != "" {
+		location += "?" + encoded
+	}
+	c.Redirect(http.StatusMovedPermanently, location)
+}
+
+func (h *Handler) listNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	cursor := c.Query("cursor")
+	limitStr := c.Query("limit")
+	tag := c.Query("tag")
+	category := c.Query("category")
+	// Only set on /authors/:id/news
+	authorID := c.Param("id")
+	clientIP := c.ClientIP()
+	logger.Debug("article list request received", slog.String("cursor", cursor), slog.String("limit", limitStr), slog.String("tag", tag),
+		slog.String("category", category), slog.String("author_id", authorID), slog.String("client_ip", clientIP))
+
+	// If the limit is present, it must be a positive number
+	limit := 0
+	if limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			logger.Warn("invalid request: limit must be a positive number", slog.String("limit", limitStr), slog.String("client_ip", clientIP))
+			renderError(c, http.StatusBadRequest, codeInvalidLimit, "limit must be a positive number")
+			return
+		}
+		limit = parsed
+	}
+
+	preview, ok := previewMode(c)
+	if !ok {
+		return
+	}
+
+	// Get the requested page from the service and handle any errors
+	opts := ListOptions{Cursor: cursor, Limit: limit, Tag: tag, Category: category, AuthorID: authorID, Preview: preview, Status: c.Query("status")}
+	page, err := h.svc.List(c.Request.Context(), opts)
+	if errors.Is(err, ErrInvalidCursor) {
+		logger.Warn("invalid request: invalid cursor", slog.String("cursor", cursor), slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeInvalidCursor, "invalid cursor")
+		return
+	}
+	if errors.Is(err, ErrInvalidStatus) {
+		logger.Warn("invalid request: invalid status", slog.String("status", opts.Status), slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeInvalidStatus, "status must be one of: draft, scheduled, published, archived")
+		return
+	}
+	if err != nil {
+		logger.Error("error listing articles", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusInternalServerError, codeInternalError, "failed to list articles")
+		return
+	}
+
+	// Return the page rendered as JSON or HTML depending on the client preferences
+	logger.Info("article list request successful", slog.Int("count", len(page.Articles)), slog.String("client_ip", clientIP))
+	render(c, http.StatusOK, "news_list.html", page, page.ToOutput())
+}
+
+func (h *Handler) searchNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	query := c.Query("q")
+	language := c.Query("lang")
+	clientIP := c.ClientIP()
+	logger.Debug("article search request received", slog.String("query", query), slog.String("language", language), slog.String("client_ip", clientIP))
+
+	limit, err := parseNonNegativeQuery(c, "limit")
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidLimit, "limit must be a positive number")
+		return
+	}
+	offset, err := parseNonNegativeQuery(c, "offset")
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidOffset, "offset must be a positive number")
+		return
+	}
+
+	// Search articles through the service and handle any errors
+	page, err := h.svc.Search(c.Request.Context(), query, SearchOptions{Language: language, Limit: limit, Offset: offset})
+	if errors.Is(err, ErrEmptySearchQuery) {
+		logger.Warn("invalid request: missing search query", slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeMissingQuery, "q parameter is required")
+		return
+	}
+	if errors.Is(err, ErrUnsupportedLanguage) {
+		logger.Warn("invalid request: unsupported language", slog.String("language", language), slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeInvalidLanguage, "lang must be one of: en, es")
+		return
+	}
+	if err != nil {
+		logger.Error("error searching articles", slog.String("query", query), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusInternalServerError, codeInternalError, "failed to search articles")
+		return
+	}
+
+	// Return the results rendered as JSON or HTML depending on the client preferences
+	logger.Info("article search request successful", slog.String("query", query), slog.Int("count", len(page.Results)), slog.String("client_ip", clientIP))
+	render(c, http.StatusOK, "news_search.html", page, page.ToOutput())
+}
+
+func (h *Handler) createNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	// Get the article from request body
+	var input ArticleInput
+	if err := bindArticle(c, &input); err != nil {
+		logger.Warn("invalid request: malformed article", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderBindError(c, err)
+		return
+	}
+
+	// The editor who creates the article is its author
+	input.Author = currentEditor(c)
+	input.Editor = input.Author
+
+	article, err := h.svc.Create(c.Request.Context(), input)
+	if err != nil {
+		logger.Warn("error creating article", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
 		return
 	}
 
-	// Return the article rendered as HTML
-	slog.Info("article request successful", slog.Uint64("id", id), slog.String("client_ip", clientIP))
-	c.HTML(http.StatusOK, "article.html", article)
+	// Send the created article and where to find it
+	logger.Info("article created", slog.Uint64("id", article.ID), slog.String("client_ip", clientIP))
+	c.Header("Location", article.URL())
+	c.JSON(http.StatusCreated, article.ToOutput())
+}
+
+func (h *Handler) updateNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return
+	}
+
+	// Get the new article contents from request body
+	var input ArticleInput
+	if err := bindArticle(c, &input); err != nil {
+		logger.Warn("invalid request: malformed article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderBindError(c, err)
+		return
+	}
+
+	input.Editor = currentEditor(c)
+	article, err := h.svc.Update(c.Request.Context(), id, input)
+	if err != nil {
+		logger.Warn("error updating article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("article updated", slog.Uint64("id", id), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, article.ToOutput())
+}
+
+func (h *Handler) patchNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return
+	}
+
+	// Get the fields to change from request body
+	var patch ArticlePatch
+	if err := bindArticle(c, &patch); err != nil {
+		logger.Warn("invalid request: malformed article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderBindError(c, err)
+		return
+	}
+
+	patch.Editor = currentEditor(c)
+	article, err := h.svc.Patch(c.Request.Context(), id, patch)
+	if err != nil {
+		logger.Warn("error patching article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("article patched", slog.Uint64("id", id), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, article.ToOutput())
+}
+
+func (h *Handler) deleteNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return
+	}
+
+	if err := h.svc.Delete(c.Request.Context(), id, currentEditor(c)); err != nil {
+		logger.Warn("error deleting article", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("article deleted", slog.Uint64("id", id), slog.String("client_ip", clientIP))
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) listTags(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	tags, err := h.svc.ListTags(c.Request.Context())
+	if err != nil {
+		logger.Error("error listing tags", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusInternalServerError, codeInternalError, "failed to list tags")
+		return
+	}
+
+	output := make([]TagOutput, 0, len(tags))
+	for i := range tags {
+		output = append(output, tags[i].ToOutput())
+	}
+	logger.Info("tag list request successful", slog.Int("count", len(tags)), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, output)
+}
+
+func (h *Handler) listCategories(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	categories, err := h.svc.ListCategories(c.Request.Context())
+	if err != nil {
+		logger.Error("error listing categories", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusInternalServerError, codeInternalError, "failed to list categories")
+		return
+	}
+
+	output := make([]CategoryOutput, 0, len(categories))
+	for i := range categories {
+		output = append(output, categories[i].ToOutput())
+	}
+	logger.Info("category list request successful", slog.Int("count", len(categories)), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, output)
+}
+
+func (h *Handler) listRevisions(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return
+	}
+
+	revisions, err := h.svc.ListRevisions(c.Request.Context(), id)
+	if err != nil {
+		logger.Warn("error listing revisions", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	output := make([]RevisionOutput, 0, len(revisions))
+	for i := range revisions {
+		output = append(output, revisions[i].ToOutput())
+	}
+	logger.Info("revision list request successful", slog.Uint64("id", id), slog.Int("count", len(revisions)), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, output)
+}
+
+func (h *Handler) getRevision(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, number, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	revision, err := h.svc.GetRevision(c.Request.Context(), id, number)
+	if err != nil {
+		logger.Warn("error fetching revision", slog.Uint64("id", id), slog.Int("revision", number), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("revision request successful", slog.Uint64("id", id), slog.Int("revision", number), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, revision.ToOutput())
+}
+
+func (h *Handler) restoreRevision(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, number, ok := parseRevisionParams(c)
+	if !ok {
+		return
+	}
+
+	article, err := h.svc.Restore(c.Request.Context(), id, number, currentEditor(c))
+	if err != nil {
+		logger.Warn("error restoring revision", slog.Uint64("id", id), slog.Int("revision", number), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("revision restored", slog.Uint64("id", id), slog.Int("revision", number), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, article.ToOutput())
+}
+
+func (h *Handler) diffRevisions(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	clientIP := c.ClientIP()
+
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return
+	}
+	from, fromErr := parseRevisionNumber(c.Query("from"))
+	to, toErr := parseRevisionNumber(c.Query("to"))
+	if fromErr != nil || toErr != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidRevision, "from and to must be revision numbers")
+		return
+	}
+	mode := c.Query("mode")
+
+	diff, err := h.svc.DiffRevisions(c.Request.Context(), id, from, to, mode)
+	if errors.Is(err, ErrInvalidDiff) {
+		logger.Warn("invalid request: invalid diff mode", slog.String("mode", mode), slog.String("client_ip", clientIP))
+		renderError(c, http.StatusBadRequest, codeInvalidDiffMode, "mode must be line or word")
+		return
+	}
+	if err != nil {
+		logger.Warn("error diffing revisions", slog.Uint64("id", id), slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	// Return the diff rendered as JSON or HTML depending on the client preferences
+	logger.Info("revision diff request successful", slog.Uint64("id", id), slog.Int("from", from), slog.Int("to", to), slog.String("client_ip", clientIP))
+	render(c, http.StatusOK, "news_diff.html", diff, diff.ToOutput())
+}
+
+// Parse the article id and the revision number of the path, the error is rendered when any is invalid
+func parseRevisionParams(c *gin.Context) (id uint64, number int, ok bool) {
+	id, err := validateAndParseID(c.Param("id"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidID, "id must be a valid number")
+		return 0, 0, false
+	}
+	number, err = parseRevisionNumber(c.Param("revision"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidRevision, "revision must be a positive number")
+		return 0, 0, false
+	}
+	return id, number, true
+}
+
+// Revisions are numbered from 1
+func parseRevisionNumber(value string) (int, error) {
+	number, err := strconv.Atoi(value)
+	if err != nil || number <= 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return number, nil
+}
+
+// The logged-in user who is writing, nil when the routes are not authenticated
+func currentEditor(c *gin.Context) *Author {
+	current, ok := user.CurrentUser(c)
+	if !ok {
+		return nil
+	}
+	return &Author{ID: current.ID, Name: current.Username}
+}
+
+// Check whether the client asked for the preview mode, which shows unpublished
+// articles to editors. The error is rendered when the mode is not allowed.
+func previewMode(c *gin.Context) (preview bool, ok bool) {
+	logger := logging.FromContext(c.Request.Context())
+
+	value := c.Query("preview")
+	if value == "" {
+		return false, true
+	}
+
+	preview, err := strconv.ParseBool(value)
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidPreview, "preview must be true or false")
+		return false, false
+	}
+	if !preview {
+		return false, true
+	}
+
+	current, loggedIn := user.CurrentUser(c)
+	if !loggedIn {
+		renderError(c, http.StatusUnauthorized, codeAuthRequired, "authentication required to preview articles")
+		return false, false
+	}
+	if !current.HasRole(user.RoleEditor) {
+		logger.Warn("access denied: preview requires the editor role", slog.String("username", current.Username), slog.String("client_ip", c.ClientIP()))
+		renderError(c, http.StatusForbidden, codeForbidden, "only editors can preview articles")
+		return false, false
+	}
+	return true, true
+}
+
+// Parse an optional non-negative integer query parameter, missing values are returned as zero
+func parseNonNegativeQuery(c *gin.Context, key string) (int, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, strconv.ErrSyntax
+	}
+	return parsed, nil
+}
+
+// Decode the JSON request body, rejecting documents bigger than maxRequestBodyBytes
+func bindArticle(c *gin.Context, obj any) error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRequestBodyBytes)
+	return c.ShouldBindJSON(obj)
+}
+
+// Reject a request body bindArticle could not decode, too large bodies get a 413
+func renderBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		renderError(c, http.StatusRequestEntityTooLarge, codeInvalidBody, "request body too large")
+		return
+	}
+	renderError(c, http.StatusBadRequest, codeInvalidBody, "invalid request body")
+}
+
+func (h *Handler) importNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	format := c.DefaultQuery("format", FormatJSONL)
+	clientIP := c.ClientIP()
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidDryRun, "dry_run must be true or false")
+		return
+	}
+
+	// The file is read from the request body as it is imported
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodyBytes)
+	reader, err := NewRecordReader(format, c.Request.Body)
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidFormat, "format must be jsonl or csv")
+		return
+	}
+
+	report, err := h.svc.Import(c.Request.Context(), reader, ImportOptions{DryRun: dryRun})
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		logger.Warn("invalid request: import file too large", slog.String("client_ip", clientIP))
+		renderError(c, http.StatusRequestEntityTooLarge, codeInvalidBody, "import file too large")
+		return
+	}
+	if errors.Is(err, ErrInvalidImportFile) {
+		logger.Warn("invalid request: invalid import file", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderError(c, http.StatusBadRequest, codeInvalidBody, err.Error())
+		return
+	}
+	if err != nil {
+		logger.Error("error importing articles", slog.String("client_ip", clientIP), slog.Any("error", err))
+		renderServiceError(c, err)
+		return
+	}
+
+	logger.Info("articles imported", slog.Bool("dry_run", dryRun), slog.Int("inserted", report.Inserted),
+		slog.Int("updated", report.Updated), slog.Int("invalid", report.Invalid), slog.String("client_ip", clientIP))
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) exportNews(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	format := c.DefaultQuery("format", FormatJSONL)
+	clientIP := c.ClientIP()
+
+	writer, err := NewRecordWriter(format, c.Writer)
+	if err != nil {
+		renderError(c, http.StatusBadRequest, codeInvalidFormat, "format must be jsonl or csv")
+		return
+	}
+
+	contentType := "application/x-ndjson"
+	if format == FormatCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", `attachment; filename="news.`+format+`"`)
+	c.Status(http.StatusOK)
+
+	// The status has already been sent once the articles are streamed, errors can only be logged
+	count, err := h.svc.Export(c.Request.Context(), writer)
+	if err != nil {
+		logger.Error("error exporting articles", slog.Int("count", count), slog.String("client_ip", clientIP), slog.Any("error", err))
+		return
+	}
+	logger.Info("articles exported", slog.Int("count", count), slog.String("client_ip", clientIP))
 }
@@ -4,32 +4,155 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"html/template"
 
+	"github.com/ManuelJNunez/news_service/internal/user"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
 
 type stubService struct {
-	article *Article
-	err     error
+	article    *Article
+	page       *Page
+	search     *SearchPage
+	err        error
+	lastOpts   ListOptions
+	report     *ImportReport
+	imported   []Record
+	dryRun     bool
+	exported   []Record
+	tags       []Tag
+	categories []Category
+	lastInput  ArticleInput
+	previewed  bool
+	published  []Article
+	revisions  []Revision
+	revision   *Revision
+	diff       *RevisionDiff
+	lastMode   string
+	restoredBy *Author
+	lastSlug   string
 }
 
 func (s *stubService) GetByID(_ context.Context, _ uint64) (*Article, error) {
 	return s.article, s.err
 }
 
+func (s *stubService) Preview(_ context.Context, _ uint64) (*Article, error) {
+	s.previewed = true
+	return s.article, s.err
+}
+
+func (s *stubService) GetBySlug(_ context.Context, slug string) (*Article, error) {
+	s.lastSlug = slug
+	return s.article, s.err
+}
+
+func (s *stubService) PreviewBySlug(_ context.Context, slug string) (*Article, error) {
+	s.lastSlug = slug
+	s.previewed = true
+	return s.article, s.err
+}
+
+func (s *stubService) PublishScheduled(_ context.Context) ([]Article, error) {
+	return s.published, s.err
+}
+
+func (s *stubService) List(_ context.Context, opts ListOptions) (*Page, error) {
+	s.lastOpts = opts
+	return s.page, s.err
+}
+
+func (s *stubService) Create(_ context.Context, input ArticleInput) (*Article, error) {
+	s.lastInput = input
+	return s.article, s.err
+}
+
+func (s *stubService) Update(_ context.Context, _ uint64, _ ArticleInput) (*Article, error) {
+	return s.article, s.err
+}
+
+func (s *stubService) Patch(_ context.Context, _ uint64, _ ArticlePatch) (*Article, error) {
+	return s.article, s.err
+}
+
+func (s *stubService) Delete(_ context.Context, _ uint64, _ *Author) error {
+	return s.err
+}
+
+func (s *stubService) Search(_ context.Context, _ string, _ SearchOptions) (*SearchPage, error) {
+	return s.search, s.err
+}
+
+func (s *stubService) Import(_ context.Context, r RecordReader, opts ImportOptions) (*ImportReport, error) {
+	s.dryRun = opts.DryRun
+	for {
+		record, _, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.imported = append(s.imported, record)
+	}
+	return s.report, s.err
+}
+
+func (s *stubService) Export(_ context.Context, w RecordWriter) (int, error) {
+	for _, record := range s.exported {
+		if err := w.Write(record); err != nil {
+			return 0, err
+		}
+	}
+	return len(s.exported), w.Flush()
+}
+
+func (s *stubService) ListTags(_ context.Context) ([]Tag, error) {
+	return s.tags, s.err
+}
+
+func (s *stubService) ListCategories(_ context.Context) ([]Category, error) {
+	return s.categories, s.err
+}
+
+func (s *stubService) ListRevisions(_ context.Context, _ uint64) ([]Revision, error) {
+	return s.revisions, s.err
+}
+
+func (s *stubService) GetRevision(_ context.Context, _ uint64, _ int) (*Revision, error) {
+	return s.revision, s.err
+}
+
+func (s *stubService) DiffRevisions(_ context.Context, _ uint64, _ int, _ int, mode string) (*RevisionDiff, error) {
+	s.lastMode = mode
+	return s.diff, s.err
+}
+
+func (s *stubService) Restore(_ context.Context, _ uint64, _ int, editor *Author) (*Article, error) {
+	s.restoredBy = editor
+	return s.article, s.err
+}
+
 func setupRouter(svc Service) *gin.Engine {
 	gin.SetMode(gin.TestMode)
 	r := gin.New()
 	// Create a fake HTML template
-	r.SetHTMLTemplate(template.Must(template.New("article.html").Parse("{{.Title}}|{{.Body}}")))
+	tmpl := template.Must(template.New("article.html").Parse("{{.Title}}|{{.Body}}"))
+	template.Must(tmpl.New("news_list.html").Parse("{{range .Articles}}{{.Title}};{{end}}|{{.NextCursor}}"))
+	template.Must(tmpl.New("news_search.html").Parse("{{range .Results}}{{.Title}}:{{.Snippet}};{{end}}"))
+	template.Must(tmpl.New("news_diff.html").Parse("{{range .Body}}{{.Op}}:{{.Text}};{{end}}"))
+	r.SetHTMLTemplate(tmpl)
 	RegisterRoutes(r.Group(""), NewHandler(svc))
+	RegisterAdminRoutes(r.Group("/admin"), NewHandler(svc))
 	return r
 }
 
@@ -107,3 +230,706 @@ func TestHandlerGetNewsSuccess(t *testing.T) {
 	body := w.Body.String()
 	assert.Contains(t, body, "fake_title|fake_body")
 }
+
+func TestHandlerGetNewsRedirectsToSlug(t *testing.T) {
+	article := &Article{ID: 2, Title: "Wannacry: el ransomware que alertó a todo el mundo", Slug: "wannacry-el-ransomware-que-alerto-a-todo-el-mundo"}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news?id=2&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/news/wannacry-el-ransomware-que-alerto-a-todo-el-mundo?format=json", w.Header().Get("Location"))
+}
+
+func TestHandlerGetNewsBySlug(t *testing.T) {
+	article := &Article{ID: 2, Title: "fake_title", Body: "fake_body", Status: StatusPublished, Slug: "fake-title"}
+	svc := &stubService{article: article}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/fake-title", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "fake-title", svc.lastSlug)
+	assert.Contains(t, w.Body.String(), "fake_title|fake_body")
+
+	// Slugs are matched once decoded
+	article.Slug = "новости"
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/news/%D0%BD%D0%BE%D0%B2%D0%BE%D1%81%D1%82%D0%B8?format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"slug":"новости"`)
+}
+
+func TestHandlerGetNewsByPreviousSlug(t *testing.T) {
+	article := &Article{ID: 2, Title: "Go 1.26 Released", Slug: "go-1-26-released"}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/go-1-25-released", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/news/go-1-26-released", w.Header().Get("Location"))
+}
+
+func TestHandlerGetNewsBySlugNotFound(t *testing.T) {
+	router := setupRouter(&stubService{err: ErrArticleNotFound})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/missing", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), codeArticleNotFound)
+}
+
+func TestHandlerListNewsHTML(t *testing.T) {
+	page := &Page{
+		Articles:   []Article{{ID: 2, Title: "second"}, {ID: 1, Title: "first"}},
+		NextCursor: "next",
+	}
+	svc := &stubService{page: page}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list?limit=2&cursor=abc", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "second;first;|next")
+	assert.Equal(t, ListOptions{Cursor: "abc", Limit: 2}, svc.lastOpts)
+}
+
+func TestHandlerListNewsNextPageKeepsLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.SetHTMLTemplate(template.Must(template.ParseFiles("../../templates/news_list.html")))
+	page := &Page{Articles: []Article{{ID: 2, Title: "second"}}, NextCursor: "next", Tag: "go", Limit: 2}
+	RegisterRoutes(r.Group(""), NewHandler(&stubService{page: page}))
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list?limit=2&tag=go", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `href="/news/list?cursor=next&tag=go&limit=2"`)
+}
+
+func TestHandlerListNewsJSON(t *testing.T) {
+	datetime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	page := &Page{
+		Articles:   []Article{{ID: 7, Title: "fake_title", Body: "fake_body", Datetime: datetime}},
+		NextCursor: "next",
+	}
+	router := setupRouter(&stubService{page: page})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list", nil)
+	req.Header.Set("Accept", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp PageOutput
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "next", resp.NextCursor)
+	assert.Equal(t, []ArticleOutput{{ID: 7, Title: "fake_title", Body: "fake_body", Datetime: "2025-01-02T03:04:05Z", Tags: []string{}}}, resp.Articles)
+}
+
+func TestHandlerGetNewsFiltered(t *testing.T) {
+	svc := &stubService{page: &Page{Articles: []Article{{ID: 1, Title: "fake_title"}}}}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news?tag=security&category=tech&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ListOptions{Tag: "security", Category: "tech"}, svc.lastOpts)
+}
+
+func TestHandlerListAuthorNews(t *testing.T) {
+	svc := &stubService{page: &Page{Articles: []Article{{ID: 1, Title: "fake_title", Author: &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}}}}}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/authors/64b7f0c2a1b2c3d4e5f60718/news?limit=5&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, ListOptions{Limit: 5, AuthorID: "64b7f0c2a1b2c3d4e5f60718"}, svc.lastOpts)
+
+	var resp PageOutput
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, &AuthorOutput{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, resp.Articles[0].Author)
+}
+
+func TestHandlerPreview(t *testing.T) {
+	cases := []struct {
+		user    *user.UserOutput
+		query   string
+		status  int
+		preview bool
+	}{
+		{nil, "/news?id=1&preview=true", http.StatusUnauthorized, false},
+		{&user.UserOutput{Username: "bob", Roles: []string{user.RoleReader}}, "/news?id=1&preview=true", http.StatusForbidden, false},
+		{&user.UserOutput{Username: "alice", Roles: []string{user.RoleEditor}}, "/news?id=1&preview=maybe", http.StatusBadRequest, false},
+		{&user.UserOutput{Username: "alice", Roles: []string{user.RoleEditor}}, "/news?id=1&preview=true", http.StatusOK, true},
+		{nil, "/news?id=1&preview=false", http.StatusOK, false},
+	}
+
+	for _, tc := range cases {
+		gin.SetMode(gin.TestMode)
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if tc.user != nil {
+				c.Set(user.ContextUserKey, tc.user)
+			}
+		})
+		svc := &stubService{article: &Article{ID: 1, Status: StatusDraft}}
+		RegisterRoutes(r.Group(""), NewHandler(svc))
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.query+"&format=json", nil)
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.query)
+		assert.Equal(t, tc.preview, svc.previewed, tc.query)
+	}
+}
+
+func TestHandlerListNewsInvalidStatus(t *testing.T) {
+	router := setupRouter(&stubService{err: fmt.Errorf("%w %q", ErrInvalidStatus, "deleted")})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list?status=deleted", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), codeInvalidStatus)
+}
+
+func TestHandlerListNewsInvalidLimit(t *testing.T) {
+	router := setupRouter(&stubService{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list?limit=-1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "limit must be a positive number", resp["error"])
+}
+
+func TestHandlerListNewsInvalidCursor(t *testing.T) {
+	router := setupRouter(&stubService{err: ErrInvalidCursor})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list?cursor=bogus", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var resp map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, "invalid cursor", resp["error"])
+}
+
+func TestHandlerListNewsInternalError(t *testing.T) {
+	router := setupRouter(&stubService{err: errors.New("db down")})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/list", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestHandlerCreateNewsSuccess(t *testing.T) {
+	article := &Article{ID: 42, Title: "fake_title", Body: "fake_body", Datetime: time.Now(), Slug: "fake-title"}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/news", strings.NewReader(`{"title":"fake_title","body":"fake_body"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "/news/fake-title", w.Header().Get("Location"))
+
+	var resp ArticleOutput
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(42), resp.ID)
+	assert.Equal(t, "fake_title", resp.Title)
+}
+
+func TestHandlerCreateNewsSetsAuthor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	svc := &stubService{article: &Article{ID: 42}}
+	login := func(c *gin.Context) {
+		c.Set(user.ContextUserKey, &user.UserOutput{ID: "64b7f0c2a1b2c3d4e5f60718", Username: "alice"})
+	}
+	RegisterRoutes(r.Group(""), NewHandler(svc), login)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/news", strings.NewReader(`{"title":"fake_title","body":"fake_body","author":{"id":"forged"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, svc.lastInput.Author)
+}
+
+func TestHandlerCreateNewsMalformedBody(t *testing.T) {
+	router := setupRouter(&stubService{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/news", strings.NewReader(`{"title":`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestHandlerCreateNewsBodyTooLarge(t *testing.T) {
+	router := setupRouter(&stubService{article: &Article{}})
+
+	body := fmt.Sprintf(`{"title":"fake_title","body":"%s"}`, strings.Repeat("a", maxRequestBodyBytes))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/news", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.JSONEq(t, `{"code":"invalid_body","error":"request body too large"}`, w.Body.String())
+}
+
+func TestHandlerPatchNewsBodyTooLarge(t *testing.T) {
+	router := setupRouter(&stubService{article: &Article{}})
+
+	body := fmt.Sprintf(`{"body":"%s"}`, strings.Repeat("a", maxRequestBodyBytes))
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/news/1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestHandlerCreateNewsErrors(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+	}{
+		{fmt.Errorf("%w: title is required", ErrInvalidArticle), http.StatusBadRequest},
+		{ErrArticleAlreadyExists, http.StatusConflict},
+		{ErrCategoryNotFound, http.StatusBadRequest},
+		{errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range cases {
+		router := setupRouter(&stubService{err: tc.err})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/news", strings.NewReader(`{"title":"","body":""}`))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.err.Error())
+	}
+}
+
+func TestHandlerUpdateNewsNotFound(t *testing.T) {
+	router := setupRouter(&stubService{err: ErrArticleNotFound})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPut, "/news/1", strings.NewReader(`{"title":"t","body":"b"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlerPatchNewsSuccess(t *testing.T) {
+	article := &Article{ID: 1, Title: "new_title", Body: "fake_body", Datetime: time.Now()}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPatch, "/news/1", strings.NewReader(`{"title":"new_title"}`))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "new_title")
+}
+
+func TestHandlerDeleteNews(t *testing.T) {
+	router := setupRouter(&stubService{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/news/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	router = setupRouter(&stubService{err: ErrArticleNotFound})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandlerEditorRoutesUseMiddlewares(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	deny := func(c *gin.Context) { c.AbortWithStatus(http.StatusUnauthorized) }
+	RegisterRoutes(r.Group(""), NewHandler(&stubService{}), deny)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodDelete, "/news/1", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// The revisions may hold unpublished contents
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/news/1/revisions", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestHandlerListRevisions(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	svc := &stubService{revisions: []Revision{
+		{ArticleID: 1, Number: 2, Title: "new_title", Editor: &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, CreatedAt: createdAt},
+		{ArticleID: 1, Number: 1, Title: "old_title", CreatedAt: createdAt},
+	}}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1/revisions", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[
+		{"article_id":1,"revision":2,"title":"new_title","tags":[],"editor":{"id":"64b7f0c2a1b2c3d4e5f60718","name":"alice"},"created_at":"2025-01-01T00:00:00Z"},
+		{"article_id":1,"revision":1,"title":"old_title","tags":[],"created_at":"2025-01-01T00:00:00Z"}
+	]`, w.Body.String())
+}
+
+func TestHandlerGetRevision(t *testing.T) {
+	revision := &Revision{ArticleID: 1, Number: 1, Title: "old_title", Body: "old_body", Tags: []string{"go"}}
+	router := setupRouter(&stubService{revision: revision})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1/revisions/1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"body":"old_body"`)
+
+	cases := []struct {
+		path   string
+		err    error
+		status int
+		code   string
+	}{
+		{"/news/1/revisions/0", nil, http.StatusBadRequest, codeInvalidRevision},
+		{"/news/abc/revisions/1", nil, http.StatusBadRequest, codeInvalidID},
+		{"/news/1/revisions/9", ErrRevisionNotFound, http.StatusNotFound, codeRevisionNotFound},
+	}
+	for _, tc := range cases {
+		router := setupRouter(&stubService{err: tc.err})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.path, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.path)
+		assert.Contains(t, w.Body.String(), tc.code, tc.path)
+	}
+}
+
+func TestHandlerDiffRevisions(t *testing.T) {
+	from := &Revision{ArticleID: 1, Number: 1, Body: "old body"}
+	to := &Revision{ArticleID: 1, Number: 2, Body: "new body"}
+	svc := &stubService{diff: newRevisionDiff(from, to, DiffWords)}
+	router := setupRouter(svc)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1/diff?from=1&to=2&mode=word&format=html", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, DiffWords, svc.lastMode)
+	assert.Equal(t, "delete:old;insert:new;equal: body;", w.Body.String())
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/news/1/diff?from=1&to=2&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp RevisionDiffOutput
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.True(t, resp.Changed)
+	assert.Equal(t, 2, resp.To)
+	assert.Equal(t, []DiffOp{}, resp.Title)
+}
+
+func TestHandlerDiffRevisionsErrors(t *testing.T) {
+	cases := []struct {
+		query  string
+		err    error
+		status int
+		code   string
+	}{
+		{"from=1", nil, http.StatusBadRequest, codeInvalidRevision},
+		{"from=a&to=2", nil, http.StatusBadRequest, codeInvalidRevision},
+		{"from=1&to=2&mode=char", fmt.Errorf("%w: mode must be line or word", ErrInvalidDiff), http.StatusBadRequest, codeInvalidDiffMode},
+		{"from=1&to=9", ErrRevisionNotFound, http.StatusNotFound, codeRevisionNotFound},
+		{"from=1&to=2", ErrArticleNotFound, http.StatusNotFound, codeArticleNotFound},
+	}
+
+	for _, tc := range cases {
+		router := setupRouter(&stubService{err: tc.err})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/news/1/diff?"+tc.query, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.query)
+		assert.Contains(t, w.Body.String(), tc.code, tc.query)
+	}
+}
+
+func TestHandlerRestoreRevision(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	svc := &stubService{article: &Article{ID: 1, Title: "old_title"}}
+	login := func(c *gin.Context) {
+		c.Set(user.ContextUserKey, &user.UserOutput{ID: "64b7f0c2a1b2c3d4e5f60718", Username: "alice"})
+	}
+	RegisterRoutes(r.Group(""), NewHandler(svc), login)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/news/1/revisions/1/restore", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "old_title")
+	assert.Equal(t, &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, svc.restoredBy)
+}
+
+func TestHandlerGetNewsJSONFromAcceptHeader(t *testing.T) {
+	datetime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	article := &Article{ID: 99, Title: "fake_title", Body: "fake_body", Datetime: datetime, Category: "tech", Tags: []string{"go"}, Status: StatusPublished}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news?id=99", nil)
+	req.Header.Set("Accept", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"id":99,"title":"fake_title","body":"fake_body","datetime":"2025-01-02T03:04:05Z","category":"tech","tags":["go"],"status":"published"}`, w.Body.String())
+}
+
+func TestHandlerGetNewsFormatOverride(t *testing.T) {
+	article := &Article{ID: 99, Title: "fake_title", Body: "fake_body", Datetime: time.Now()}
+	router := setupRouter(&stubService{article: article})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news?id=99&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+
+	w = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/news?id=99&format=html", nil)
+	req.Header.Set("Accept", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "fake_title|fake_body")
+}
+
+func TestHandlerErrorEnvelope(t *testing.T) {
+	router := setupRouter(&stubService{err: ErrArticleNotFound})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news?id=1", nil)
+	router.ServeHTTP(w, req)
+
+	var resp ErrorOutput
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, ErrorOutput{Code: codeArticleNotFound, Error: "article not found"}, resp)
+}
+
+func TestHandlerSearchNewsHTML(t *testing.T) {
+	search := &SearchPage{
+		Query:   "ransomware",
+		Results: []SearchResult{{Article: Article{ID: 2, Title: "Wannacry"}, Snippet: highlightSnippet("el \x02ransomware\x03 <b>")}},
+	}
+	router := setupRouter(&stubService{search: search})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/search?q=ransomware", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Wannacry:el <mark>ransomware</mark> &lt;b&gt;;")
+}
+
+func TestHandlerSearchNewsJSON(t *testing.T) {
+	search := &SearchPage{
+		Query:      "go",
+		Results:    []SearchResult{{Article: Article{ID: 3, Title: "Go 1.25 Released"}, Rank: 0.5, Snippet: "<mark>Go</mark>"}},
+		NextOffset: 10,
+	}
+	router := setupRouter(&stubService{search: search})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/search?q=go&format=json", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var resp SearchPageOutput
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, resp.NextOffset)
+	assert.Equal(t, "<mark>Go</mark>", resp.Results[0].Snippet)
+	assert.Equal(t, uint64(3), resp.Results[0].ID)
+}
+
+func TestHandlerSearchNewsErrors(t *testing.T) {
+	cases := []struct {
+		url    string
+		err    error
+		status int
+		code   string
+	}{
+		{"/news/search", ErrEmptySearchQuery, http.StatusBadRequest, codeMissingQuery},
+		{"/news/search?q=go&lang=fr", ErrUnsupportedLanguage, http.StatusBadRequest, codeInvalidLanguage},
+		{"/news/search?q=go&offset=-1", nil, http.StatusBadRequest, codeInvalidOffset},
+		{"/news/search?q=go", errors.New("db down"), http.StatusInternalServerError, codeInternalError},
+	}
+
+	for _, tc := range cases {
+		router := setupRouter(&stubService{err: tc.err})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, tc.url, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.url)
+
+		var resp ErrorOutput
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, resp.Code, tc.url)
+	}
+}
+
+func TestHandlerListTags(t *testing.T) {
+	router := setupRouter(&stubService{tags: []Tag{{Name: "security", Count: 3}}})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/tags", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[{"name":"security","count":3}]`, w.Body.String())
+}
+
+func TestHandlerListCategories(t *testing.T) {
+	router := setupRouter(&stubService{})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/categories", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `[]`, w.Body.String())
+}
+
+func TestHandlerImportNews(t *testing.T) {
+	svc := &stubService{report: &ImportReport{DryRun: true, Processed: 2, Valid: 2}}
+	router := setupRouter(svc)
+
+	body := "external_id,title,body\nwp-1,First,Body\nwp-2,Second,\n"
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/admin/news/import?format=csv&dry_run=true", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, svc.dryRun)
+	assert.Equal(t, []Record{{ExternalID: "wp-1", Title: "First", Body: "Body"}, {ExternalID: "wp-2", Title: "Second"}}, svc.imported)
+
+	var resp ImportReport
+	err := json.Unmarshal(w.Body.Bytes(), &resp)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resp.Valid)
+}
+
+func TestHandlerImportNewsErrors(t *testing.T) {
+	cases := []struct {
+		url    string
+		body   string
+		status int
+		code   string
+	}{
+		{"/admin/news/import?format=xml", "", http.StatusBadRequest, codeInvalidFormat},
+		{"/admin/news/import?dry_run=maybe", "", http.StatusBadRequest, codeInvalidDryRun},
+		{"/admin/news/import?format=csv", "title\nFirst\n", http.StatusBadRequest, codeInvalidBody},
+	}
+
+	for _, tc := range cases {
+		router := setupRouter(&stubService{})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, tc.url, strings.NewReader(tc.body))
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.url)
+
+		var resp ErrorOutput
+		err := json.Unmarshal(w.Body.Bytes(), &resp)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.code, resp.Code, tc.url)
+	}
+}
+
+func TestHandlerExportNews(t *testing.T) {
+	datetime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	router := setupRouter(&stubService{exported: []Record{
+		{ID: 1, ExternalID: "wp-1", Title: "First, again", Body: "Body", Datetime: datetime, Tags: []string{"go", "security"}, Status: StatusDraft},
+	}})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/admin/news/export?format=csv", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="news.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,external_id,title,body,datetime,category,tags,status,publish_at,author_id,author_name\n"+
+		"1,wp-1,\"First, again\",Body,2024-05-01T10:00:00Z,,\"go,security\",draft,,,\n", w.Body.String())
+}
@@ -0,0 +1,21 @@
+package news
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var articlesServed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "news_articles_served_total",
+	Help: "Number of articles served by format (html or json).",
+}, []string{"format"})
+
+var scheduledArticlesPublished = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "news_scheduled_articles_published_total",
+	Help: "Number of scheduled articles published by the scheduler.",
+})
+
+var schedulerErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "news_scheduler_errors_total",
+	Help: "Number of scheduler runs that failed to publish the due articles.",
+})
@@ -1,9 +1,208 @@
 package news
 
-import "time"
+import (
+	"net/url"
+	"time"
+)
+
+// Statuses of an article, only published articles are served to the public
+const (
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+var statuses = []string{StatusDraft, StatusScheduled, StatusPublished, StatusArchived}
 
 type Article struct {
+	ID       uint64
 	Title    string
 	Body     string
 	Datetime time.Time
+	Category string
+	Tags     []string
+	Author   *Author
+	Status   string
+	// PublishAt is when a scheduled article gets published
+	PublishAt *time.Time
+	// Slug identifies the article in its URL, it is generated from the title
+	Slug string
+}
+
+// URL of the article page
+func (a *Article) URL() string {
+	return "/news/" + url.PathEscape(a.Slug)
+}
+
+// Published reports whether the article can be served to the public
+func (a *Article) Published() bool {
+	return a.Status == StatusPublished
+}
+
+// Author of an article, the user account that created it. Name is the
+// username when the article was created, kept for the byline.
+type Author struct {
+	ID   string
+	Name string
+}
+
+// AuthorOutput for API responses
+type AuthorOutput struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ArticleOutput for API responses
+type ArticleOutput struct {
+	ID        uint64        `json:"id"`
+	Title     string        `json:"title"`
+	Body      string        `json:"body"`
+	Datetime  string        `json:"datetime"`
+	Category  string        `json:"category,omitempty"`
+	Tags      []string      `json:"tags"`
+	Author    *AuthorOutput `json:"author,omitempty"`
+	Status    string        `json:"status"`
+	PublishAt string        `json:"publish_at,omitempty"`
+	Slug      string        `json:"slug,omitempty"`
+}
+
+// Convert Article to ArticleOutput
+func (a *Article) ToOutput() ArticleOutput {
+	tags := a.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	output := ArticleOutput{
+		ID:       a.ID,
+		Title:    a.Title,
+		Body:     a.Body,
+		Datetime: a.Datetime.Format(time.RFC3339),
+		Category: a.Category,
+		Tags:     tags,
+		Status:   a.Status,
+		Slug:     a.Slug,
+	}
+	if a.Author != nil {
+		output.Author = &AuthorOutput{ID: a.Author.ID, Name: a.Author.Name}
+	}
+	if a.PublishAt != nil {
+		output.PublishAt = a.PublishAt.Format(time.RFC3339)
+	}
+	return output
+}
+
+// Options received when listing articles, Tag, Category and AuthorID filter the articles.
+// Only published articles are listed unless Preview is set, then Status filters them.
+type ListOptions struct {
+	Cursor   string
+	Limit    int
+	Tag      string
+	Category string
+	AuthorID string
+	Preview  bool
+	Status   string
+}
+
+// Filter of the articles listed by the repository, empty fields match every article
+type Filter struct {
+	Tag      string
+	Category string
+	AuthorID string
+	Status   string
+}
+
+// Page of articles returned by a listing, NextCursor is empty on the last page.
+// The filters used are kept for the link to the next page.
+type Page struct {
+	Articles   []Article
+	NextCursor string
+	Tag        string
+	Category   string
+	AuthorID   string
+	Preview    bool
+	Status     string
+	Limit      int
+}
+
+// PageOutput for API responses
+type PageOutput struct {
+	Articles   []ArticleOutput `json:"articles"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+// Convert Page to PageOutput
+func (p *Page) ToOutput() PageOutput {
+	articles := make([]ArticleOutput, 0, len(p.Articles))
+	for i := range p.Articles {
+		articles = append(articles, p.Articles[i].ToOutput())
+	}
+
+	return PageOutput{
+		Articles:   articles,
+		NextCursor: p.NextCursor,
+	}
+}
+
+// Data received when creating or replacing an article. Author is set to the
+// logged-in user on creation and Editor on every save, they are never read
+// from the request body.
+type ArticleInput struct {
+	Title    string   `json:"title"`
+	Body     string   `json:"body"`
+	Category string   `json:"category"`
+	Tags     []string `json:"tags"`
+	Author   *Author  `json:"-"`
+	Editor   *Author  `json:"-"`
+	// Status defaults to scheduled when PublishAt is set and to published otherwise
+	Status    string     `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// Data received when partially updating an article, nil fields are left untouched
+type ArticlePatch struct {
+	Title     *string    `json:"title"`
+	Body      *string    `json:"body"`
+	Category  *string    `json:"category"`
+	Tags      *[]string  `json:"tags"`
+	Status    *string    `json:"status"`
+	PublishAt *time.Time `json:"publish_at"`
+	Editor    *Author    `json:"-"`
+}
+
+// Tag assigned to Count articles
+type Tag struct {
+	Name  string
+	Count int
+}
+
+// TagOutput for API responses
+type TagOutput struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Convert Tag to TagOutput
+func (t *Tag) ToOutput() TagOutput {
+	return TagOutput{Name: t.Name, Count: t.Count}
+}
+
+// Category with Count articles
+type Category struct {
+	Slug  string
+	Name  string
+	Count int
+}
+
+// CategoryOutput for API responses
+type CategoryOutput struct {
+	Slug  string `json:"slug"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Convert Category to CategoryOutput
+func (c *Category) ToOutput() CategoryOutput {
+	return CategoryOutput{Slug: c.Slug, Name: c.Name, Count: c.Count}
 }
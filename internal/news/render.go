@@ -0,0 +1,88 @@
+package news
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error codes sent in the JSON error envelope
+const (
+	codeMissingID        = "missing_id"
+	codeInvalidID        = "invalid_id"
+	codeInvalidLimit     = "invalid_limit"
+	codeInvalidCursor    = "invalid_cursor"
+	codeInvalidOffset    = "invalid_offset"
+	codeInvalidLanguage  = "invalid_language"
+	codeMissingQuery     = "missing_query"
+	codeInvalidBody      = "invalid_body"
+	codeInvalidFormat    = "invalid_format"
+	codeInvalidDryRun    = "invalid_dry_run"
+	codeInvalidArticle   = "invalid_article"
+	codeArticleNotFound  = "article_not_found"
+	codeArticleExists    = "article_already_exists"
+	codeUnknownCategory  = "unknown_category"
+	codeInvalidPreview   = "invalid_preview"
+	codeInvalidStatus    = "invalid_status"
+	codeAuthRequired     = "authentication_required"
+	codeForbidden        = "forbidden"
+	codeInvalidRevision  = "invalid_revision"
+	codeRevisionNotFound = "revision_not_found"
+	codeInvalidDiffMode  = "invalid_diff_mode"
+	codeInternalError    = "internal_error"
+)
+
+// ErrorOutput is the JSON envelope used for every error response
+type ErrorOutput struct {
+	Code  string `json:"code"`
+	Error string `json:"error"`
+}
+
+// Check whether the client asked for JSON. The format query parameter
+// (json or html) takes precedence over the Accept header.
+func wantsJSON(c *gin.Context) bool {
+	switch c.Query("format") {
+	case "json":
+		return true
+	case "html":
+		return false
+	}
+
+	c.Header("Vary", "Accept")
+	return c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+}
+
+// Render data as JSON or as the given HTML template depending on the client
+// preferences, returns the chosen format ("json" or "html")
+func render(c *gin.Context, status int, template string, data any, output any) string {
+	if wantsJSON(c) {
+		c.JSON(status, output)
+		return "json"
+	}
+	c.HTML(status, template, data)
+	return "html"
+}
+
+// Send an error using the JSON error envelope
+func renderError(c *gin.Context, status int, code string, message string) {
+	c.AbortWithStatusJSON(status, ErrorOutput{Code: code, Error: message})
+}
+
+// Map the errors returned by the service write operations to HTTP responses
+func renderServiceError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidArticle):
+		renderError(c, http.StatusBadRequest, codeInvalidArticle, err.Error())
+	case errors.Is(err, ErrArticleNotFound):
+		renderError(c, http.StatusNotFound, codeArticleNotFound, "article not found")
+	case errors.Is(err, ErrRevisionNotFound):
+		renderError(c, http.StatusNotFound, codeRevisionNotFound, "revision not found")
+	case errors.Is(err, ErrCategoryNotFound):
+		renderError(c, http.StatusBadRequest, codeUnknownCategory, "unknown category")
+	case errors.Is(err, ErrArticleAlreadyExists):
+		renderError(c, http.StatusConflict, codeArticleExists, "article already exists")
+	default:
+		renderError(c, http.StatusInternalServerError, codeInternalError, "internal server error")
+	}
+}
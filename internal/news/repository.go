@@ -4,14 +4,74 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/tracing"
+	"github.com/lib/pq"
 )
 
 // ErrNotFound is used when it is not possible to find the requested Article.
 var ErrArticleNotFound = errors.New("article not found")
 
+// ErrArticleAlreadyExists is used when another article already has the same title.
+var ErrArticleAlreadyExists = errors.New("article already exists")
+
+// ErrCategoryNotFound is used when assigning a category that does not exist.
+var ErrCategoryNotFound = errors.New("category not found")
+
+// PostgreSQL error codes raised when a unique or a foreign key constraint is violated
+const (
+	uniqueViolationCode     = "23505"
+	foreignKeyViolationCode = "23503"
+)
+
+// Columns with the category and the sorted tags of the article in the news row
+const classificationColumns = `coalesce(category, ''),
+	ARRAY(SELECT t.name FROM news_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.news_id = news.id ORDER BY t.name)`
+
+// Author of the articles, scanned with authorScanner
+const authorColumns = "coalesce(author_id, ''), coalesce(author_name, '')"
+
+// Publishing status of the articles
+const statusColumns = "status, publish_at"
+
+// Slug of the articles, only null while the transaction that creates them assigns it
+const slugColumn = "coalesce(slug, '')"
+
+// Snapshot of the content of the article $1 as its next revision, saved by the editor $2 and $3. It runs in
+// the transaction that saves the article, whose row lock gives concurrent saves consecutive numbers.
+const addRevisionQuery = `INSERT INTO news_revisions (news_id, revision, title, body, category, tags, status, datetime, editor_id, editor_name)
+SELECT id, coalesce((SELECT max(revision) FROM news_revisions WHERE news_id = $1), 0) + 1, title, body, category,
+	ARRAY(SELECT t.name FROM news_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.news_id = news.id ORDER BY t.name),
+	status, datetime, NULLIF($2, ''), NULLIF($3, '')
+FROM news WHERE id = $1;`
+
+// Last revision of the article $1, deleted by the editor $2 and $3, kept once the article is gone
+const deleteRevisionQuery = `INSERT INTO news_revisions (news_id, revision, title, body, category, tags, status, datetime, editor_id, editor_name)
+SELECT id, coalesce((SELECT max(revision) FROM news_revisions WHERE news_id = $1), 0) + 1, title, body, category,
+	ARRAY(SELECT t.name FROM news_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.news_id = news.id ORDER BY t.name),
+	'` + RevisionDeleted + `', datetime, NULLIF($2, ''), NULLIF($3, '')
+FROM news WHERE id = $1;`
+
 type Repository interface {
 	GetByID(ctx context.Context, id uint64) (*Article, error)
+	GetBySlug(ctx context.Context, slug string) (*Article, error)
+	List(ctx context.Context, filter Filter, after *Cursor, limit int) ([]Article, error)
+	Create(ctx context.Context, input ArticleInput) (*Article, error)
+	Update(ctx context.Context, id uint64, input ArticleInput) (*Article, error)
+	Delete(ctx context.Context, id uint64, editor *Author) error
+	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
+	UpsertBatch(ctx context.Context, records []Record) (inserted int, updated int, err error)
+	Export(ctx context.Context, fn func(record Record) error) error
+	ListTags(ctx context.Context) ([]Tag, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	PublishDue(ctx context.Context, now time.Time) ([]Article, error)
+	ListRevisions(ctx context.Context, id uint64) ([]Revision, error)
+	GetRevision(ctx context.Context, id uint64, number int) (*Revision, error)
 }
 
 type postgresRepository struct {
@@ -23,28 +83,871 @@ func NewRepository(db *sql.DB) Repository {
 }
 
 func (s *postgresRepository) GetByID(ctx context.Context, id uint64) (*Article, error) {
-	slog.Debug("fetching article", slog.Uint64("id", id))
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("fetching article", slog.Uint64("id", id))
 
-	const query = "SELECT title, body, datetime FROM news WHERE id=$1;"
+	const query = "SELECT title, body, datetime, " + classificationColumns + ", " + authorColumns + ", " + statusColumns + ", " + slugColumn + " FROM news WHERE id=$1;"
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news", query)
+	defer span.End()
 
 	// Get a single row from the database (the first one) and copy the fetched data into the Article struct
-	var article Article
+	article := Article{ID: id}
+	var author authorScanner
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
 		&article.Title,
 		&article.Body,
 		&article.Datetime,
+		&article.Category,
+		pq.Array(&article.Tags),
+		&author.id,
+		&author.name,
+		&article.Status,
+		&article.PublishAt,
+		&article.Slug,
 	)
+	article.Author = author.author()
 
 	// Check error returned by the query
 	if errors.Is(err, sql.ErrNoRows) {
-		slog.Warn("article not found", slog.Uint64("id", id))
+		logger.Warn("article not found", slog.Uint64("id", id))
+		return nil, ErrArticleNotFound
+	}
+	if err != nil {
+		logger.Error("error fetching article", slog.Uint64("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully fetched article", slog.Uint64("id", id))
+	return &article, nil
+}
+
+// GetBySlug returns the article with the given slug, or the article that had it before its title changed
+func (s *postgresRepository) GetBySlug(ctx context.Context, slug string) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("fetching article by slug", slog.String("slug", slug))
+
+	const query = "SELECT id, title, body, datetime, " + classificationColumns + ", " + authorColumns + ", " + statusColumns + ", " + slugColumn + ` FROM news
+WHERE slug = $1 OR id = (SELECT news_id FROM news_slugs WHERE slug = $1);`
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news", query)
+	defer span.End()
+
+	var article Article
+	var author authorScanner
+	err := s.db.QueryRowContext(ctx, query, slug).Scan(
+		&article.ID,
+		&article.Title,
+		&article.Body,
+		&article.Datetime,
+		&article.Category,
+		pq.Array(&article.Tags),
+		&author.id,
+		&author.name,
+		&article.Status,
+		&article.PublishAt,
+		&article.Slug,
+	)
+	article.Author = author.author()
+
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("article not found", slog.String("slug", slug))
 		return nil, ErrArticleNotFound
 	}
 	if err != nil {
-		slog.Error("error fetching article", slog.Uint64("id", id), slog.Any("error", err))
+		logger.Error("error fetching article by slug", slog.String("slug", slug), slog.Any("error", err))
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	slog.Info("successfully fetched article", slog.Uint64("id", id))
+	logger.Info("successfully fetched article by slug", slog.String("slug", slug), slog.Uint64("id", article.ID))
 	return &article, nil
 }
+
+func (s *postgresRepository) List(ctx context.Context, filter Filter, after *Cursor, limit int) ([]Article, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("listing articles", slog.Int("limit", limit), slog.Bool("has_cursor", after != nil),
+		slog.String("tag", filter.Tag), slog.String("category", filter.Category), slog.String("author_id", filter.AuthorID), slog.String("status", filter.Status))
+
+	// Only the conditions are chosen here, the values are always sent as parameters
+	var conditions []string
+	var args []any
+	param := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	// Keyset pagination: continue right after the last article of the previous page
+	if after != nil {
+		conditions = append(conditions, fmt.Sprintf("(datetime, id) < (%s, %s)", param(after.Datetime), param(after.ID)))
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "category = "+param(filter.Category))
+	}
+	if filter.AuthorID != "" {
+		conditions = append(conditions, "author_id = "+param(filter.AuthorID))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "status = "+param(filter.Status))
+	}
+	if filter.Tag != "" {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM news_tags nt JOIN tags t ON t.id = nt.tag_id WHERE nt.news_id = news.id AND t.name = "+param(filter.Tag)+")")
+	}
+
+	query := "SELECT id, title, body, datetime, " + classificationColumns + ", " + authorColumns + ", " + statusColumns + ", " + slugColumn + " FROM news"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY datetime DESC, id DESC LIMIT " + param(limit) + ";"
+
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news", query)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		logger.Error("error listing articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	articles := make([]Article, 0, limit)
+	for rows.Next() {
+		var article Article
+		var author authorScanner
+		if err := rows.Scan(&article.ID, &article.Title, &article.Body, &article.Datetime, &article.Category, pq.Array(&article.Tags), &author.id, &author.name,
+			&article.Status, &article.PublishAt, &article.Slug); err != nil {
+			logger.Error("error scanning article", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		article.Author = author.author()
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully listed articles", slog.Int("count", len(articles)))
+	return articles, nil
+}
+
+// Create inserts the article with its slug, its tags and its first revision in a single transaction
+func (s *postgresRepository) Create(ctx context.Context, input ArticleInput) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("creating article", slog.String("title", input.Title))
+
+	const query = `INSERT INTO news (title, body, category, author_id, author_name, status, publish_at)
+VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6, $7) RETURNING id, datetime;`
+	ctx, span := tracing.StartSQL(ctx, "INSERT", "news", query)
+	defer span.End()
+
+	// Articles created outside the API (e.g. tests or scripts) may have no author
+	var author Author
+	if input.Author != nil {
+		author = *input.Author
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error starting transaction", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Insert the article and get the values generated by the database
+	article := Article{Title: input.Title, Body: input.Body, Category: input.Category, Tags: input.Tags, Author: input.Author, Status: input.Status, PublishAt: input.PublishAt}
+	err = tx.QueryRowContext(ctx, query, input.Title, input.Body, input.Category, author.ID, author.Name, input.Status, input.PublishAt).
+		Scan(&article.ID, &article.Datetime)
+	if isForeignKeyViolation(err) {
+		logger.Warn("category not found", slog.String("category", input.Category))
+		return nil, ErrCategoryNotFound
+	}
+	if isUniqueViolation(err) {
+		logger.Warn("article already exists", slog.String("title", input.Title))
+		return nil, ErrArticleAlreadyExists
+	}
+	if err != nil {
+		logger.Error("error creating article", slog.String("title", input.Title), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	article.Slug, err = assignSlug(ctx, tx, article.ID, "", slugify(input.Title))
+	if err == nil && len(input.Tags) > 0 {
+		err = setTags(ctx, tx, article.ID, input.Tags)
+	}
+	if err == nil {
+		err = addRevision(ctx, tx, article.ID, input.Editor)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logger.Error("error creating article", slog.String("title", input.Title), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully created article", slog.Uint64("id", article.ID), slog.String("slug", article.Slug))
+	return &article, nil
+}
+
+// Update replaces the article and its tags and records the result as a new revision, in a single transaction
+func (s *postgresRepository) Update(ctx context.Context, id uint64, input ArticleInput) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("updating article", slog.Uint64("id", id))
+
+	// Drafts and scheduled articles are dated when they get published
+	const query = `UPDATE news SET title=$1, body=$2, category=NULLIF($3, ''), status=$4, publish_at=$5,
+	datetime = CASE WHEN status IN ('draft', 'scheduled') AND $4 = 'published' THEN CURRENT_TIMESTAMP ELSE datetime END
+WHERE id=$6 RETURNING datetime, ` + authorColumns + ", " + slugColumn + ";"
+	ctx, span := tracing.StartSQL(ctx, "UPDATE", "news", query)
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error starting transaction", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Replace the article contents, the author never changes. No returned row means there is no article with that ID
+	article := Article{ID: id, Title: input.Title, Body: input.Body, Category: input.Category, Tags: input.Tags, Status: input.Status, PublishAt: input.PublishAt}
+	var author authorScanner
+	var currentSlug string
+	err = tx.QueryRowContext(ctx, query, input.Title, input.Body, input.Category, input.Status, input.PublishAt, id).
+		Scan(&article.Datetime, &author.id, &author.name, &currentSlug)
+	article.Author = author.author()
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("article not found", slog.Uint64("id", id))
+		return nil, ErrArticleNotFound
+	}
+	if isForeignKeyViolation(err) {
+		logger.Warn("category not found", slog.String("category", input.Category))
+		return nil, ErrCategoryNotFound
+	}
+	if isUniqueViolation(err) {
+		logger.Warn("article already exists", slog.String("title", input.Title))
+		return nil, ErrArticleAlreadyExists
+	}
+	if err != nil {
+		logger.Error("error updating article", slog.Uint64("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	article.Slug, err = assignSlug(ctx, tx, id, currentSlug, slugify(input.Title))
+	if err == nil {
+		err = setTags(ctx, tx, id, input.Tags)
+	}
+	if err == nil {
+		err = addRevision(ctx, tx, id, input.Editor)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logger.Error("error updating article", slog.Uint64("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully updated article", slog.Uint64("id", id), slog.String("slug", article.Slug))
+	return &article, nil
+}
+
+// Delete removes the article and records its last content as a revision with the
+// deleted status, so its history is kept
+func (s *postgresRepository) Delete(ctx context.Context, id uint64, editor *Author) error {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("deleting article", slog.Uint64("id", id))
+
+	const query = "DELETE FROM news WHERE id=$1;"
+	ctx, span := tracing.StartSQL(ctx, "DELETE", "news", query)
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error starting transaction", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Lock the article so the revision numbers of concurrent saves do not clash
+	var locked uint64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM news WHERE id=$1 FOR UPDATE;", id).Scan(&locked)
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("article not found", slog.Uint64("id", id))
+		return ErrArticleNotFound
+	}
+	if err == nil {
+		var author Author
+		if editor != nil {
+			author = *editor
+		}
+		_, err = tx.ExecContext(ctx, deleteRevisionQuery, id, author.ID, author.Name)
+	}
+	if err == nil {
+		_, err = tx.ExecContext(ctx, query, id)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		logger.Error("error deleting article", slog.Uint64("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	logger.Info("successfully deleted article", slog.Uint64("id", id))
+	return nil
+}
+
+func (s *postgresRepository) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("searching articles", slog.String("query", query), slog.String("language", opts.Language))
+
+	config, ok := searchConfigs[opts.Language]
+	if !ok {
+		return nil, ErrUnsupportedLanguage
+	}
+
+	// The text search expressions come from searchConfigs, user input is only sent as parameters
+	sqlQuery := fmt.Sprintf(`SELECT n.id, n.title, n.body, n.datetime, coalesce(n.slug, ''), ts_rank(n.search_vector, q.query) AS rank,
+	ts_headline('%s', coalesce(n.body, ''), q.highlight, $4) AS snippet
+FROM news n, (SELECT %s AS query, %s AS highlight) q
+WHERE n.search_vector @@ q.query AND n.status = 'published'
+ORDER BY rank DESC, n.datetime DESC, n.id DESC
+LIMIT $2 OFFSET $3;`, config.headline, config.query, config.highlight)
+
+	const headlineOptions = "StartSel=" + highlightStart + ", StopSel=" + highlightStop + ", MaxFragments=2, MaxWords=30, MinWords=10"
+
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news", sqlQuery)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, sqlQuery, query, opts.Limit, opts.Offset, headlineOptions)
+	if err != nil {
+		logger.Error("error searching articles", slog.String("query", query), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	results := make([]SearchResult, 0, opts.Limit)
+	for rows.Next() {
+		var result SearchResult
+		var snippet string
+		if err := rows.Scan(&result.ID, &result.Title, &result.Body, &result.Datetime, &result.Slug, &result.Rank, &snippet); err != nil {
+			logger.Error("error scanning search result", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		result.Snippet = highlightSnippet(snippet)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating search results", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully searched articles", slog.String("query", query), slog.Int("count", len(results)))
+	return results, nil
+}
+
+// UpsertBatch inserts or updates the records by external id in a single transaction
+func (s *postgresRepository) UpsertBatch(ctx context.Context, records []Record) (int, int, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("upserting articles", slog.Int("count", len(records)))
+
+	// A missing datetime or author keeps the one of the existing article. xmax is only zero for inserted rows.
+	const query = `INSERT INTO news (external_id, title, body, datetime, category, status, publish_at, author_id, author_name)
+VALUES ($1, $2, $3, COALESCE($4, CURRENT_TIMESTAMP), NULLIF($5, ''), $6, $7, NULLIF($8, ''), NULLIF($9, ''))
+ON CONFLICT (external_id) DO UPDATE SET title=EXCLUDED.title, body=EXCLUDED.body, datetime=COALESCE($4, news.datetime),
+	category=EXCLUDED.category, status=EXCLUDED.status, publish_at=EXCLUDED.publish_at,
+	author_id=COALESCE(EXCLUDED.author_id, news.author_id), author_name=COALESCE(EXCLUDED.author_name, news.author_name)
+RETURNING id, (xmax = 0) AS inserted, coalesce(slug, '');`
+	ctx, span := tracing.StartSQL(ctx, "INSERT", "news", query)
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error starting transaction", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return 0, 0, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		logger.Error("error preparing upsert", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return 0, 0, err
+	}
+	defer stmt.Close() //nolint:errcheck
+
+	// Imported contents are recorded as revisions without an editor
+	revisionStmt, err := tx.PrepareContext(ctx, addRevisionQuery)
+	if err != nil {
+		logger.Error("error preparing revision", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return 0, 0, err
+	}
+	defer revisionStmt.Close() //nolint:errcheck
+
+	inserted, updated := 0, 0
+	for _, record := range records {
+		datetime := sql.NullTime{Time: record.Datetime, Valid: !record.Datetime.IsZero()}
+
+		var id uint64
+		var isInsert bool
+		var slug string
+		err := stmt.QueryRowContext(ctx, record.ExternalID, record.Title, record.Body, datetime,
+			record.Category, record.Status, record.PublishAt, record.AuthorID, record.AuthorName).Scan(&id, &isInsert, &slug)
+		if isForeignKeyViolation(err) {
+			logger.Warn("category not found", slog.String("external_id", record.ExternalID), slog.String("category", record.Category))
+			return 0, 0, fmt.Errorf("%w: external_id %s", ErrCategoryNotFound, record.ExternalID)
+		}
+		if isUniqueViolation(err) {
+			logger.Warn("article already exists", slog.String("external_id", record.ExternalID), slog.String("title", record.Title))
+			return 0, 0, fmt.Errorf("%w: external_id %s", ErrArticleAlreadyExists, record.ExternalID)
+		}
+		if err != nil {
+			logger.Error("error upserting article", slog.String("external_id", record.ExternalID), slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return 0, 0, err
+		}
+		if _, err := assignSlug(ctx, tx, id, slug, slugify(record.Title)); err != nil {
+			logger.Error("error assigning slug", slog.String("external_id", record.ExternalID), slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return 0, 0, err
+		}
+		if err := setTags(ctx, tx, id, record.Tags); err != nil {
+			logger.Error("error tagging article", slog.String("external_id", record.ExternalID), slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return 0, 0, err
+		}
+		if _, err := revisionStmt.ExecContext(ctx, id, "", ""); err != nil {
+			logger.Error("error recording revision", slog.String("external_id", record.ExternalID), slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return 0, 0, err
+		}
+
+		if isInsert {
+			inserted++
+		} else {
+			updated++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing upsert", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return 0, 0, err
+	}
+
+	logger.Info("successfully upserted articles", slog.Int("inserted", inserted), slog.Int("updated", updated))
+	return inserted, updated, nil
+}
+
+// Export calls fn with every article ordered by id, rows are streamed instead of loaded in memory
+func (s *postgresRepository) Export(ctx context.Context, fn func(record Record) error) error {
+	logger := logging.FromContext(ctx)
+
+	const query = "SELECT id, coalesce(external_id, ''), title, coalesce(body, ''), datetime, " +
+		classificationColumns + ", " + statusColumns + ", " + authorColumns + " FROM news ORDER BY id;"
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news", query)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logger.Error("error exporting articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	count := 0
+	for rows.Next() {
+		var record Record
+		err := rows.Scan(&record.ID, &record.ExternalID, &record.Title, &record.Body, &record.Datetime, &record.Category,
+			pq.Array(&record.Tags), &record.Status, &record.PublishAt, &record.AuthorID, &record.AuthorName)
+		if err != nil {
+			logger.Error("error scanning article", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+
+	logger.Info("successfully exported articles", slog.Int("count", count))
+	return nil
+}
+
+// Replace the tags of the article, the tags that do not exist yet are created
+func setTags(ctx context.Context, tx *sql.Tx, id uint64, tags []string) error {
+	const createTags = "INSERT INTO tags (name) SELECT unnest($1::text[]) ON CONFLICT (name) DO NOTHING;"
+	const deleteTags = "DELETE FROM news_tags WHERE news_id=$1;"
+	const assignTags = "INSERT INTO news_tags (news_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2);"
+
+	if _, err := tx.ExecContext(ctx, createTags, pq.Array(tags)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, deleteTags, id); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, assignTags, id, pq.Array(tags))
+	return err
+}
+
+// ListTags returns the tags assigned to any published article, the most used first
+func (s *postgresRepository) ListTags(ctx context.Context) ([]Tag, error) {
+	logger := logging.FromContext(ctx)
+
+	const query = `SELECT t.name, count(*) FROM tags t
+JOIN news_tags nt ON nt.tag_id = t.id
+JOIN news n ON n.id = nt.news_id AND n.status = 'published'
+GROUP BY t.name ORDER BY count(*) DESC, t.name;`
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "tags", query)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logger.Error("error listing tags", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	tags := []Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			logger.Error("error scanning tag", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating tags", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully listed tags", slog.Int("count", len(tags)))
+	return tags, nil
+}
+
+// ListCategories returns every category with the number of published articles in it
+func (s *postgresRepository) ListCategories(ctx context.Context) ([]Category, error) {
+	logger := logging.FromContext(ctx)
+
+	const query = `SELECT c.slug, c.name, count(n.id) FROM categories c
+LEFT JOIN news n ON n.category = c.slug AND n.status = 'published'
+GROUP BY c.slug, c.name ORDER BY c.name;`
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "categories", query)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		logger.Error("error listing categories", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	categories := []Category{}
+	for rows.Next() {
+		var category Category
+		if err := rows.Scan(&category.Slug, &category.Name, &category.Count); err != nil {
+			logger.Error("error scanning category", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		categories = append(categories, category)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating categories", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully listed categories", slog.Int("count", len(categories)))
+	return categories, nil
+}
+
+// PublishDue publishes the scheduled articles whose publish_at is not after
+// now, dating them on their publish_at, and returns them. Every article is
+// returned once, even when several instances publish at the same time.
+func (s *postgresRepository) PublishDue(ctx context.Context, now time.Time) ([]Article, error) {
+	logger := logging.FromContext(ctx)
+
+	const query = `UPDATE news SET status = 'published', datetime = publish_at
+WHERE status = 'scheduled' AND publish_at <= $1
+RETURNING id, title, datetime, publish_at;`
+	ctx, span := tracing.StartSQL(ctx, "UPDATE", "news", query)
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		logger.Error("error starting transaction", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	articles, err := publishDue(ctx, tx, query, now)
+	if err != nil {
+		logger.Error("error publishing scheduled articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	// Publishing is recorded as a revision without an editor, the rows are still locked by the update
+	for _, article := range articles {
+		if err := addRevision(ctx, tx, article.ID, nil); err != nil {
+			logger.Error("error recording revision", slog.Uint64("id", article.ID), slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		logger.Error("error committing published articles", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return articles, nil
+}
+
+// Run the publishing query in tx and return the articles it published
+func publishDue(ctx context.Context, tx *sql.Tx, query string, now time.Time) ([]Article, error) {
+	rows, err := tx.QueryContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	var articles []Article
+	for rows.Next() {
+		article := Article{Status: StatusPublished}
+		if err := rows.Scan(&article.ID, &article.Title, &article.Datetime, &article.PublishAt); err != nil {
+			return nil, err
+		}
+		articles = append(articles, article)
+	}
+	return articles, rows.Err()
+}
+
+// Record the content of the article saved in tx as its next revision
+func addRevision(ctx context.Context, tx *sql.Tx, id uint64, editor *Author) error {
+	var author Author
+	if editor != nil {
+		author = *editor
+	}
+	_, err := tx.ExecContext(ctx, addRevisionQuery, id, author.ID, author.Name)
+	return err
+}
+
+// ListRevisions returns the revisions of an article without their body, the newest first
+func (s *postgresRepository) ListRevisions(ctx context.Context, id uint64) ([]Revision, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("listing revisions", slog.Uint64("id", id))
+
+	const query = `SELECT revision, title, coalesce(category, ''), tags, coalesce(status, ''), datetime, coalesce(editor_id, ''),
+	coalesce(editor_name, ''), created_at
+FROM news_revisions WHERE news_id = $1 ORDER BY revision DESC;`
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news_revisions", query)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, query, id)
+	if err != nil {
+		logger.Error("error listing revisions", slog.Uint64("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	revisions := []Revision{}
+	for rows.Next() {
+		revision := Revision{ArticleID: id}
+		var editor authorScanner
+		var datetime sql.NullTime
+		if err := rows.Scan(&revision.Number, &revision.Title, &revision.Category, pq.Array(&revision.Tags), &revision.Status, &datetime,
+			&editor.id, &editor.name, &revision.CreatedAt); err != nil {
+			logger.Error("error scanning revision", slog.Any("error", err))
+			tracing.RecordError(span, err)
+			return nil, err
+		}
+		revision.Editor = editor.author()
+		if datetime.Valid {
+			revision.Datetime = &datetime.Time
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		logger.Error("error iterating revisions", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully listed revisions", slog.Uint64("id", id), slog.Int("count", len(revisions)))
+	return revisions, nil
+}
+
+func (s *postgresRepository) GetRevision(ctx context.Context, id uint64, number int) (*Revision, error) {
+	logger := logging.FromContext(ctx)
+
+	logger.Debug("fetching revision", slog.Uint64("id", id), slog.Int("revision", number))
+
+	const query = `SELECT title, coalesce(body, ''), coalesce(category, ''), tags, coalesce(status, ''), datetime, coalesce(editor_id, ''),
+	coalesce(editor_name, ''), created_at
+FROM news_revisions WHERE news_id = $1 AND revision = $2;`
+	ctx, span := tracing.StartSQL(ctx, "SELECT", "news_revisions", query)
+	defer span.End()
+
+	revision := Revision{ArticleID: id, Number: number}
+	var editor authorScanner
+	var datetime sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id, number).Scan(
+		&revision.Title,
+		&revision.Body,
+		&revision.Category,
+		pq.Array(&revision.Tags),
+		&revision.Status,
+		&datetime,
+		&editor.id,
+		&editor.name,
+		&revision.CreatedAt,
+	)
+	revision.Editor = editor.author()
+	if datetime.Valid {
+		revision.Datetime = &datetime.Time
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		logger.Warn("revision not found", slog.Uint64("id", id), slog.Int("revision", number))
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		logger.Error("error fetching revision", slog.Uint64("id", id), slog.Int("revision", number), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("successfully fetched revision", slog.Uint64("id", id), slog.Int("revision", number))
+	return &revision, nil
+}
+
+// Give the article the slug base, or base followed by the first free number,
+// and keep its current slug to redirect it. The article keeps its slug when it
+// is base, or base with a number while another article still has base.
+func assignSlug(ctx context.Context, tx *sql.Tx, id uint64, current string, base string) (string, error) {
+	if current == base {
+		return current, nil
+	}
+
+	// Articles getting the same slug at the same time wait for each other until the end of the transaction
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1));", base); err != nil {
+		return "", err
+	}
+
+	// Slugs only have letters, digits and dashes, so base needs no escaping in LIKE
+	rows, err := tx.QueryContext(ctx, "SELECT slug FROM news WHERE (slug = $1 OR slug LIKE $1 || '-%') AND id <> $2;", base, id)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	taken := map[string]bool{}
+	for _, slug := range reservedSlugs {
+		taken[slug] = true
+	}
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return "", err
+		}
+		taken[slug] = true
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	// The number was only needed while base was taken
+	if slugFrom(current, base) && taken[base] {
+		return current, nil
+	}
+
+	slug := base
+	for n := 2; taken[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE news SET slug = $1 WHERE id = $2;", slug, id); err != nil {
+		return "", err
+	}
+	// The new slug stops redirecting to the article that had it before
+	if _, err := tx.ExecContext(ctx, "DELETE FROM news_slugs WHERE slug = $1;", slug); err != nil {
+		return "", err
+	}
+	if current != "" {
+		const keepSlug = "INSERT INTO news_slugs (slug, news_id) VALUES ($1, $2) ON CONFLICT (slug) DO UPDATE SET news_id = EXCLUDED.news_id;"
+		if _, err := tx.ExecContext(ctx, keepSlug, current, id); err != nil {
+			return "", err
+		}
+	}
+	return slug, nil
+}
+
+// Check whether slug is base followed by the number that made it unique
+func slugFrom(slug string, base string) bool {
+	suffix, ok := strings.CutPrefix(slug, base+"-")
+	return ok && suffix != "" && strings.Trim(suffix, "0123456789") == ""
+}
+
+// Destination of authorColumns, articles without an author have an empty id
+type authorScanner struct {
+	id   string
+	name string
+}
+
+func (a *authorScanner) author() *Author {
+	if a.id == "" {
+		return nil
+	}
+	return &Author{ID: a.id, Name: a.name}
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolationCode
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolationCode
+}
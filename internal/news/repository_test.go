@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -40,10 +41,11 @@ func TestGetByIDSuccess(t *testing.T) {
 		Datetime: time.Now(),
 	}
 
-	rows := sqlmock.NewRows([]string{"title", "body", "datetime"}).
-		AddRow(expectedArticle.Title, expectedArticle.Body, expectedArticle.Datetime)
+	publishAt := expectedArticle.Datetime.Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"title", "body", "datetime", "category", "tags", "author_id", "author_name", "status", "publish_at", "slug"}).
+		AddRow(expectedArticle.Title, expectedArticle.Body, expectedArticle.Datetime, "security", "{malware,ransomware}", "64b7f0c2a1b2c3d4e5f60718", "alice", StatusScheduled, publishAt, "test-article")
 
-	mock.ExpectQuery("SELECT title, body, datetime FROM news WHERE id=\\$1").
+	mock.ExpectQuery("SELECT title, body, datetime, .+ FROM news WHERE id=\\$1").
 		WithArgs(uint64(1)).
 		WillReturnRows(rows)
 
@@ -54,6 +56,12 @@ func TestGetByIDSuccess(t *testing.T) {
 	assert.Equal(t, expectedArticle.Title, article.Title)
 	assert.Equal(t, expectedArticle.Body, article.Body)
 	assert.WithinDuration(t, expectedArticle.Datetime, article.Datetime, time.Second)
+	assert.Equal(t, "security", article.Category)
+	assert.Equal(t, []string{"malware", "ransomware"}, article.Tags)
+	assert.Equal(t, &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, article.Author)
+	assert.Equal(t, StatusScheduled, article.Status)
+	assert.Equal(t, &publishAt, article.PublishAt)
+	assert.Equal(t, "test-article", article.Slug)
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -67,7 +75,7 @@ func TestGetByIDNotFound(t *testing.T) {
 
 	repo := NewRepository(db)
 
-	mock.ExpectQuery("SELECT title, body, datetime FROM news WHERE id=\\$1").
+	mock.ExpectQuery("SELECT title, body, datetime, .+ FROM news WHERE id=\\$1").
 		WithArgs(uint64(999)).
 		WillReturnError(sql.ErrNoRows)
 
@@ -91,7 +99,7 @@ func TestGetByIDDatabaseError(t *testing.T) {
 
 	expectedError := errors.New("database connection error")
 
-	mock.ExpectQuery("SELECT title, body, datetime FROM news WHERE id=\\$1").
+	mock.ExpectQuery("SELECT title, body, datetime, .+ FROM news WHERE id=\\$1").
 		WithArgs(uint64(1)).
 		WillReturnError(expectedError)
 
@@ -103,3 +111,734 @@ func TestGetByIDDatabaseError(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestListFirstPage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "title", "body", "datetime", "category", "tags", "author_id", "author_name", "status", "publish_at", "slug"}).
+		AddRow(uint64(2), "Second", "Body 2", now, "tech", "{go}", "", "", StatusPublished, nil, "second").
+		AddRow(uint64(1), "First", "Body 1", now.Add(-time.Hour), "", "{}", "64b7f0c2a1b2c3d4e5f60718", "alice", StatusPublished, nil, "first")
+
+	mock.ExpectQuery("SELECT id, title, body, datetime, .+ FROM news ORDER BY datetime DESC, id DESC LIMIT \\$1").
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	articles, err := repo.List(context.Background(), Filter{}, nil, 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, articles, 2)
+	assert.Equal(t, uint64(2), articles[0].ID)
+	assert.Equal(t, "First", articles[1].Title)
+	assert.Equal(t, []string{"go"}, articles[0].Tags)
+	assert.Empty(t, articles[1].Category)
+	assert.Nil(t, articles[0].Author)
+	assert.Nil(t, articles[0].PublishAt)
+	assert.Equal(t, "alice", articles[1].Author.Name)
+	assert.Equal(t, "first", articles[1].Slug)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAfterCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	after := &Cursor{Datetime: time.Now(), ID: 5}
+	rows := sqlmock.NewRows([]string{"id", "title", "body", "datetime", "category", "tags", "author_id", "author_name", "status", "publish_at", "slug"})
+
+	mock.ExpectQuery("FROM news WHERE \\(datetime, id\\) < \\(\\$1, \\$2\\) ORDER BY").
+		WithArgs(after.Datetime, after.ID, 10).
+		WillReturnRows(rows)
+
+	articles, err := repo.List(context.Background(), Filter{}, after, 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, articles)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDatabaseError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	expectedError := errors.New("database connection error")
+
+	mock.ExpectQuery("SELECT id, title, body, datetime, .+ FROM news").
+		WithArgs(10).
+		WillReturnError(expectedError)
+
+	articles, err := repo.List(context.Background(), Filter{}, nil, 10)
+
+	assert.Error(t, err)
+	assert.Nil(t, articles)
+	assert.Equal(t, expectedError, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListFiltered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	after := &Cursor{Datetime: time.Now(), ID: 5}
+	rows := sqlmock.NewRows([]string{"id", "title", "body", "datetime", "category", "tags", "author_id", "author_name", "status", "publish_at", "slug"})
+
+	mock.ExpectQuery("WHERE \\(datetime, id\\) < \\(\\$1, \\$2\\) AND category = \\$3 AND author_id = \\$4 AND status = \\$5 AND EXISTS \\(.+t.name = \\$6\\) ORDER BY datetime DESC, id DESC LIMIT \\$7").
+		WithArgs(after.Datetime, after.ID, "tech", "64b7f0c2a1b2c3d4e5f60718", StatusPublished, "security", 10).
+		WillReturnRows(rows)
+
+	filter := Filter{Tag: "security", Category: "tech", AuthorID: "64b7f0c2a1b2c3d4e5f60718", Status: StatusPublished}
+	articles, err := repo.List(context.Background(), filter, after, 10)
+
+	assert.NoError(t, err)
+	assert.Empty(t, articles)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	now := time.Now()
+	publishAt := now.Add(time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO news \\(title, body, category, author_id, author_name, status, publish_at\\)\\s+VALUES \\(\\$1, \\$2, NULLIF\\(\\$3, ''\\), NULLIF\\(\\$4, ''\\), NULLIF\\(\\$5, ''\\), \\$6, \\$7\\) RETURNING id, datetime").
+		WithArgs("Title", "Body", "tech", "64b7f0c2a1b2c3d4e5f60718", "alice", StatusScheduled, &publishAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "datetime"}).AddRow(uint64(6), now))
+	// Another article already has the slug
+	expectAssignSlug(mock, 6, "title", []string{"title"}, "title-2", "")
+	expectSetTags(mock, 6, []string{"go"})
+	expectAddRevision(mock, 6, "64b7f0c2a1b2c3d4e5f60718", "alice")
+	mock.ExpectCommit()
+
+	author := &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}
+	input := ArticleInput{Title: "Title", Body: "Body", Category: "tech", Tags: []string{"go"}, Author: author, Status: StatusScheduled, PublishAt: &publishAt, Editor: author}
+	article, err := repo.Create(context.Background(), input)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(6), article.ID)
+	assert.Equal(t, author, article.Author)
+	assert.Equal(t, []string{"go"}, article.Tags)
+	assert.Equal(t, "Title", article.Title)
+	assert.Equal(t, "title-2", article.Slug)
+	assert.WithinDuration(t, now, article.Datetime, time.Second)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateDuplicateTitle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO news").
+		WithArgs("Title", "Body", "", "", "", StatusPublished, nil).
+		WillReturnError(&pq.Error{Code: uniqueViolationCode})
+	mock.ExpectRollback()
+
+	article, err := repo.Create(context.Background(), ArticleInput{Title: "Title", Body: "Body", Status: StatusPublished})
+
+	assert.Nil(t, article)
+	assert.Equal(t, ErrArticleAlreadyExists, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news SET title=\\$1, body=\\$2, category=NULLIF\\(\\$3, ''\\), status=\\$4, publish_at=\\$5,.+WHERE id=\\$6 RETURNING datetime, .+").
+		WithArgs("Title", "Body", "", StatusPublished, nil, uint64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"datetime", "author_id", "author_name", "slug"}).AddRow(now, "64b7f0c2a1b2c3d4e5f60718", "alice", "old-title"))
+	// The previous slug is kept to redirect it
+	expectAssignSlug(mock, 3, "title", nil, "title", "old-title")
+	expectSetTags(mock, 3, nil)
+	expectAddRevision(mock, 3, "", "")
+	mock.ExpectCommit()
+
+	article, err := repo.Update(context.Background(), 3, ArticleInput{Title: "Title", Body: "Body", Status: StatusPublished})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), article.ID)
+	assert.Equal(t, "Body", article.Body)
+	assert.Equal(t, "alice", article.Author.Name)
+	assert.Equal(t, "title", article.Slug)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateKeepsSlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	// The title still gives the slug, which got a number because another article has "title"
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news").
+		WithArgs("Title", "New body", "", StatusPublished, nil, uint64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"datetime", "author_id", "author_name", "slug"}).AddRow(time.Now(), "", "", "title-2"))
+	expectAssignSlug(mock, 3, "title", []string{"title"}, "title-2", "title-2")
+	expectSetTags(mock, 3, nil)
+	expectAddRevision(mock, 3, "", "")
+	mock.ExpectCommit()
+
+	article, err := repo.Update(context.Background(), 3, ArticleInput{Title: "Title", Body: "New body", Status: StatusPublished})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "title-2", article.Slug)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateSlugFollowsShorterTitle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	// "Top 10" renamed to "Top": top-10 looks like top with a number but "top" is free
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news").
+		WithArgs("Top", "Body", "", StatusPublished, nil, uint64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"datetime", "author_id", "author_name", "slug"}).AddRow(time.Now(), "", "", "top-10"))
+	expectAssignSlug(mock, 4, "top", []string{"top-2"}, "top", "top-10")
+	expectSetTags(mock, 4, nil)
+	expectAddRevision(mock, 4, "", "")
+	mock.ExpectCommit()
+
+	article, err := repo.Update(context.Background(), 4, ArticleInput{Title: "Top", Body: "Body", Status: StatusPublished})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "top", article.Slug)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBySlug(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "body", "datetime", "category", "tags", "author_id", "author_name", "status", "publish_at", "slug"}).
+		AddRow(uint64(2), "Wannacry: el ransomware que alertó a todo el mundo", "Body", time.Now(), "", "{}", "", "", StatusPublished, nil,
+			"wannacry-el-ransomware-que-alerto-a-todo-el-mundo")
+	mock.ExpectQuery("SELECT id, title, body, datetime, .+ FROM news\\s+WHERE slug = \\$1 OR id = \\(SELECT news_id FROM news_slugs WHERE slug = \\$1\\)").
+		WithArgs("wannacry").
+		WillReturnRows(rows)
+
+	article, err := repo.GetBySlug(context.Background(), "wannacry")
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), article.ID)
+	assert.Equal(t, "wannacry-el-ransomware-que-alerto-a-todo-el-mundo", article.Slug)
+
+	mock.ExpectQuery("FROM news").
+		WithArgs("missing").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetBySlug(context.Background(), "missing")
+
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news").
+		WithArgs("Title", "Body", "", StatusPublished, nil, uint64(999)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	article, err := repo.Update(context.Background(), 999, ArticleInput{Title: "Title", Body: "Body", Status: StatusPublished})
+
+	assert.Nil(t, article)
+	assert.Equal(t, ErrArticleNotFound, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateUnknownCategory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news").
+		WithArgs("Title", "Body", "sports", StatusPublished, nil, uint64(3)).
+		WillReturnError(&pq.Error{Code: foreignKeyViolationCode})
+	mock.ExpectRollback()
+
+	article, err := repo.Update(context.Background(), 3, ArticleInput{Title: "Title", Body: "Body", Category: "sports", Status: StatusPublished})
+
+	assert.Nil(t, article)
+	assert.Equal(t, ErrCategoryNotFound, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateRollsBackWhenTagsFail(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	tags := []string{"go", "security"}
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news").
+		WithArgs("Title", "Body", "", StatusPublished, nil, uint64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"datetime", "author_id", "author_name", "slug"}).AddRow(time.Now(), "", "", "title"))
+	mock.ExpectExec("INSERT INTO tags \\(name\\) SELECT unnest\\(\\$1::text\\[\\]\\) ON CONFLICT \\(name\\) DO NOTHING").
+		WithArgs(pq.Array(tags)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM news_tags WHERE news_id=\\$1").
+		WithArgs(uint64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO news_tags").
+		WithArgs(uint64(4), pq.Array(tags)).
+		WillReturnError(errors.New("connection reset"))
+	// Neither the article nor a revision are saved
+	mock.ExpectRollback()
+
+	_, err = repo.Update(context.Background(), 4, ArticleInput{Title: "Title", Body: "Body", Tags: tags, Status: StatusPublished})
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectQuery("SELECT t.name, count\\(\\*\\) FROM tags t JOIN news_tags nt").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).AddRow("security", 3).AddRow("go", 1))
+
+	tags, err := repo.ListTags(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Tag{{Name: "security", Count: 3}, {Name: "go", Count: 1}}, tags)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListCategories(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectQuery("SELECT c.slug, c.name, count\\(n.id\\) FROM categories c LEFT JOIN news n").
+		WillReturnRows(sqlmock.NewRows([]string{"slug", "name", "count"}).AddRow("science", "Science", 0).AddRow("tech", "Technology", 2))
+
+	categories, err := repo.ListCategories(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Category{{Slug: "science", Name: "Science"}, {Slug: "tech", Name: "Technology", Count: 2}}, categories)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPublishDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	now := time.Now()
+	publishAt := now.Add(-time.Minute)
+	mock.ExpectBegin()
+	mock.ExpectQuery("UPDATE news SET status = 'published', datetime = publish_at\\s+WHERE status = 'scheduled' AND publish_at <= \\$1").
+		WithArgs(now).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "datetime", "publish_at"}).AddRow(uint64(5), "Docker Best Practices", publishAt, publishAt))
+	// Publishing is part of the history of the article
+	expectAddRevision(mock, 5, "", "")
+	mock.ExpectCommit()
+
+	articles, err := repo.PublishDue(context.Background(), now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []Article{{ID: 5, Title: "Docker Best Practices", Datetime: publishAt, Status: StatusPublished, PublishAt: &publishAt}}, articles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateRollsBackWhenRevisionFails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO news").
+		WithArgs("Title", "Body", "", "", "", StatusPublished, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "datetime"}).AddRow(uint64(7), time.Now()))
+	expectAssignSlug(mock, 7, "title", nil, "title", "")
+	mock.ExpectExec("INSERT INTO news_revisions").
+		WithArgs(uint64(7), "", "").
+		WillReturnError(errors.New("connection reset"))
+	// The article is not left behind, so retrying does not fail with a duplicate title
+	mock.ExpectRollback()
+
+	_, err = repo.Create(context.Background(), ArticleInput{Title: "Title", Body: "Body", Status: StatusPublished})
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrArticleAlreadyExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListRevisions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	createdAt := time.Now()
+	rows := sqlmock.NewRows([]string{"revision", "title", "category", "tags", "status", "datetime", "editor_id", "editor_name", "created_at"}).
+		AddRow(2, "Go 1.26 Released", "", "{}", RevisionDeleted, createdAt, "64b7f0c2a1b2c3d4e5f60718", "alice", createdAt).
+		AddRow(1, "Go 1.26 Relased", "", "{}", "", nil, "", "", createdAt)
+	mock.ExpectQuery("SELECT revision, title, .* FROM news_revisions WHERE news_id = \\$1 ORDER BY revision DESC").
+		WithArgs(uint64(5)).
+		WillReturnRows(rows)
+
+	revisions, err := repo.ListRevisions(context.Background(), 5)
+
+	assert.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}, revisions[0].Editor)
+	assert.Equal(t, RevisionDeleted, revisions[0].Status)
+	assert.Equal(t, &createdAt, revisions[0].Datetime)
+	assert.Equal(t, 1, revisions[1].Number)
+	assert.Nil(t, revisions[1].Editor)
+	assert.Nil(t, revisions[1].Datetime)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRevisionNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectQuery("SELECT title, .* FROM news_revisions WHERE news_id = \\$1 AND revision = \\$2").
+		WithArgs(uint64(5), 9).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = repo.GetRevision(context.Background(), 5, 9)
+
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM news WHERE id=\\$1 FOR UPDATE").
+		WithArgs(uint64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(uint64(1)))
+	// The last content is kept as a deleted revision
+	mock.ExpectExec("INSERT INTO news_revisions .*\\s+'deleted', datetime").
+		WithArgs(uint64(1), "64b7f0c2a1b2c3d4e5f60718", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM news WHERE id=\\$1").
+		WithArgs(uint64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = repo.Delete(context.Background(), 1, &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT id FROM news WHERE id=\\$1 FOR UPDATE").
+		WithArgs(uint64(999)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	err = repo.Delete(context.Background(), 999, nil)
+
+	assert.Equal(t, ErrArticleNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchSuccess(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	rows := sqlmock.NewRows([]string{"id", "title", "body", "datetime", "slug", "rank", "snippet"}).
+		AddRow(uint64(2), "Wannacry", "Wannacry <script>", time.Now(), "wannacry", 0.8, "\x02Wannacry\x03 <script>")
+
+	mock.ExpectQuery("websearch_to_tsquery\\('spanish', \\$1\\)").
+		WithArgs("wannacry", 11, 0, sqlmock.AnyArg()).
+		WillReturnRows(rows)
+
+	results, err := repo.Search(context.Background(), "wannacry", SearchOptions{Language: "es", Limit: 11})
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, uint64(2), results[0].ID)
+	assert.InDelta(t, 0.8, results[0].Rank, 0.0001)
+	assert.Equal(t, "<mark>Wannacry</mark> &lt;script&gt;", string(results[0].Snippet))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchUnsupportedLanguage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	results, err := repo.Search(context.Background(), "wannacry", SearchOptions{Language: "fr", Limit: 10})
+
+	assert.Nil(t, results)
+	assert.Equal(t, ErrUnsupportedLanguage, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertBatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+	datetime := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	publishAt := datetime.Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare("INSERT INTO news \\(external_id, title, body, datetime, category, status, publish_at, author_id, author_name\\)")
+	revision := mock.ExpectPrepare("INSERT INTO news_revisions")
+	prepared.ExpectQuery().
+		WithArgs("wp-1", "First", "Body", sql.NullTime{}, "", StatusPublished, nil, "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "inserted", "slug"}).AddRow(uint64(1), true, ""))
+	expectAssignSlug(mock, 1, "first", nil, "first", "")
+	expectSetTags(mock, 1, nil)
+	revision.ExpectExec().
+		WithArgs(uint64(1), "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	prepared.ExpectQuery().
+		WithArgs("wp-2", "Second", "", sql.NullTime{Time: datetime, Valid: true}, "tech", StatusScheduled, &publishAt, "64b7f0c2a1b2c3d4e5f60718", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "inserted", "slug"}).AddRow(uint64(2), false, "second"))
+	expectSetTags(mock, 2, []string{"go"})
+	revision.ExpectExec().
+		WithArgs(uint64(2), "", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	inserted, updated, err := repo.UpsertBatch(context.Background(), []Record{
+		{ExternalID: "wp-1", Title: "First", Body: "Body", Status: StatusPublished},
+		{ExternalID: "wp-2", Title: "Second", Datetime: datetime, Category: "tech", Tags: []string{"go"}, Status: StatusScheduled, PublishAt: &publishAt,
+			AuthorID: "64b7f0c2a1b2c3d4e5f60718", AuthorName: "alice"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, inserted)
+	assert.Equal(t, 1, updated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpsertBatchRollsBackOnConflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	prepared := mock.ExpectPrepare("INSERT INTO news \\(external_id")
+	mock.ExpectPrepare("INSERT INTO news_revisions")
+	prepared.ExpectQuery().
+		WillReturnError(&pq.Error{Code: uniqueViolationCode})
+	mock.ExpectRollback()
+
+	_, _, err = repo.UpsertBatch(context.Background(), []Record{{ExternalID: "wp-1", Title: "Taken"}})
+
+	assert.ErrorIs(t, err, ErrArticleAlreadyExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close() //nolint:errcheck
+	})
+
+	repo := NewRepository(db)
+
+	publishAt := time.Now().Add(time.Hour)
+	rows := sqlmock.NewRows([]string{"id", "external_id", "title", "body", "datetime", "category", "tags", "status", "publish_at", "author_id", "author_name"}).
+		AddRow(uint64(1), "", "First", "Body", time.Now(), "", "{}", StatusPublished, nil, "", "").
+		AddRow(uint64(2), "wp-2", "Second", "", time.Now(), "tech", "{go,security}", StatusScheduled, publishAt, "64b7f0c2a1b2c3d4e5f60718", "alice")
+	mock.ExpectQuery("SELECT id, coalesce\\(external_id, ''\\), title, coalesce\\(body, ''\\), datetime, .+ FROM news ORDER BY id").
+		WillReturnRows(rows)
+
+	var records []Record
+	err = repo.Export(context.Background(), func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, "wp-2", records[1].ExternalID)
+	assert.Equal(t, []string{"go", "security"}, records[1].Tags)
+	assert.Equal(t, StatusScheduled, records[1].Status)
+	assert.Equal(t, &publishAt, records[1].PublishAt)
+	assert.Equal(t, "alice", records[1].AuthorName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Expect the statements that give the article a slug, taken holds the slugs of the other articles starting with base
+func expectSetTags(mock sqlmock.Sqlmock, id uint64, tags []string) {
+	mock.ExpectExec("INSERT INTO tags \\(name\\) SELECT unnest\\(\\$1::text\\[\\]\\) ON CONFLICT \\(name\\) DO NOTHING").
+		WithArgs(pq.Array(tags)).
+		WillReturnResult(sqlmock.NewResult(0, int64(len(tags))))
+	mock.ExpectExec("DELETE FROM news_tags WHERE news_id=\\$1").
+		WithArgs(id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO news_tags \\(news_id, tag_id\\) SELECT \\$1, id FROM tags WHERE name = ANY\\(\\$2\\)").
+		WithArgs(id, pq.Array(tags)).
+		WillReturnResult(sqlmock.NewResult(0, int64(len(tags))))
+}
+
+func expectAddRevision(mock sqlmock.Sqlmock, id uint64, editorID string, editorName string) {
+	mock.ExpectExec("INSERT INTO news_revisions \\(news_id, revision, title, body, category, tags, status, datetime, editor_id, editor_name\\)\\s+SELECT id, coalesce\\(\\(SELECT max\\(revision\\)(?s:.*)status, datetime, NULLIF").
+		WithArgs(id, editorID, editorName).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectAssignSlug(mock sqlmock.Sqlmock, id uint64, base string, taken []string, slug string, previous string) {
+	rows := sqlmock.NewRows([]string{"slug"})
+	for _, slug := range taken {
+		rows.AddRow(slug)
+	}
+
+	mock.ExpectExec("SELECT pg_advisory_xact_lock\\(hashtext\\(\\$1\\)\\)").WithArgs(base).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT slug FROM news WHERE \\(slug = \\$1 OR slug LIKE \\$1 \\|\\| '-%'\\) AND id <> \\$2").WithArgs(base, id).WillReturnRows(rows)
+	if slug == previous {
+		return
+	}
+	mock.ExpectExec("UPDATE news SET slug = \\$1 WHERE id = \\$2").WithArgs(slug, id).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM news_slugs WHERE slug = \\$1").WithArgs(slug).WillReturnResult(sqlmock.NewResult(0, 0))
+	if previous != "" {
+		mock.ExpectExec("INSERT INTO news_slugs \\(slug, news_id\\) VALUES \\(\\$1, \\$2\\) ON CONFLICT").
+			WithArgs(previous, id).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
@@ -0,0 +1,149 @@
+package news
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrRevisionNotFound is used when an article has no revision with the requested number.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ErrInvalidDiff is used when diffing revisions with an unknown granularity.
+var ErrInvalidDiff = errors.New("invalid diff")
+
+// Revision is the content of an article when it was saved, numbered from 1.
+// Editor is the user account that saved it, nil for imports.
+type Revision struct {
+	ArticleID uint64
+	Number    int
+	Title     string
+	Body      string
+	Category  string
+	Tags      []string
+	Status    string
+	Datetime  *time.Time
+	Editor    *Author
+	CreatedAt time.Time
+}
+
+// Status of the revision recorded when an article is deleted
+const RevisionDeleted = "deleted"
+
+// RevisionOutput for API responses, the body is left out of the revision lists
+type RevisionOutput struct {
+	ArticleID uint64        `json:"article_id"`
+	Number    int           `json:"revision"`
+	Title     string        `json:"title"`
+	Body      string        `json:"body,omitempty"`
+	Category  string        `json:"category,omitempty"`
+	Tags      []string      `json:"tags"`
+	Status    string        `json:"status,omitempty"`
+	Datetime  string        `json:"datetime,omitempty"`
+	Editor    *AuthorOutput `json:"editor,omitempty"`
+	CreatedAt string        `json:"created_at"`
+}
+
+// Convert Revision to RevisionOutput
+func (r *Revision) ToOutput() RevisionOutput {
+	tags := r.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	output := RevisionOutput{
+		ArticleID: r.ArticleID,
+		Number:    r.Number,
+		Title:     r.Title,
+		Body:      r.Body,
+		Category:  r.Category,
+		Tags:      tags,
+		Status:    r.Status,
+		CreatedAt: r.CreatedAt.Format(time.RFC3339),
+	}
+	if r.Datetime != nil {
+		output.Datetime = r.Datetime.Format(time.RFC3339)
+	}
+	if r.Editor != nil {
+		output.Editor = &AuthorOutput{ID: r.Editor.ID, Name: r.Editor.Name}
+	}
+	return output
+}
+
+// RevisionDiff between the From and To revisions of an article
+type RevisionDiff struct {
+	From        *Revision
+	To          *Revision
+	Granularity string
+	Title       []DiffOp
+	Body        []DiffOp
+	Category    []DiffOp
+	Tags        []DiffOp
+}
+
+// Compare two revisions of an article, the tags are compared one per line
+func newRevisionDiff(from *Revision, to *Revision, granularity string) *RevisionDiff {
+	tags := func(r *Revision) string {
+		if len(r.Tags) == 0 {
+			return ""
+		}
+		return strings.Join(r.Tags, "\n") + "\n"
+	}
+
+	return &RevisionDiff{
+		From:        from,
+		To:          to,
+		Granularity: granularity,
+		Title:       diffText(from.Title, to.Title, DiffWords),
+		Body:        diffText(from.Body, to.Body, granularity),
+		Category:    diffText(from.Category, to.Category, DiffWords),
+		Tags:        diffText(tags(from), tags(to), DiffLines),
+	}
+}
+
+// Changed reports whether the revisions differ in anything but the editor and date
+func (d *RevisionDiff) Changed() bool {
+	for _, ops := range [][]DiffOp{d.Title, d.Body, d.Category, d.Tags} {
+		for _, op := range ops {
+			if op.Op != DiffEqual {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RevisionDiffOutput for API responses
+type RevisionDiffOutput struct {
+	ArticleID   uint64   `json:"article_id"`
+	From        int      `json:"from"`
+	To          int      `json:"to"`
+	Granularity string   `json:"mode"`
+	Changed     bool     `json:"changed"`
+	Title       []DiffOp `json:"title"`
+	Body        []DiffOp `json:"body"`
+	Category    []DiffOp `json:"category"`
+	Tags        []DiffOp `json:"tags"`
+}
+
+// Convert RevisionDiff to RevisionDiffOutput
+func (d *RevisionDiff) ToOutput() RevisionDiffOutput {
+	ops := func(ops []DiffOp) []DiffOp {
+		if ops == nil {
+			return []DiffOp{}
+		}
+		return ops
+	}
+
+	return RevisionDiffOutput{
+		ArticleID:   d.From.ArticleID,
+		From:        d.From.Number,
+		To:          d.To.Number,
+		Granularity: d.Granularity,
+		Changed:     d.Changed(),
+		Title:       ops(d.Title),
+		Body:        ops(d.Body),
+		Category:    ops(d.Category),
+		Tags:        ops(d.Tags),
+	}
+}
@@ -0,0 +1,60 @@
+package news
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+)
+
+// Scheduler publishes the scheduled articles once their publish date is reached
+type Scheduler struct {
+	svc      Service
+	interval time.Duration
+}
+
+// NewScheduler checks for due articles every interval
+func NewScheduler(svc Service, interval time.Duration) *Scheduler {
+	return &Scheduler{svc: svc, interval: interval}
+}
+
+// Run publishes the due articles right away and then every interval, until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	logger.Info("publishing scheduler started", slog.Duration("interval", s.interval))
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.publishDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			logger.Info("publishing scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) publishDue(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	articles, err := s.svc.PublishScheduled(ctx)
+	if err != nil {
+		// The articles are published on the next run
+		if ctx.Err() == nil {
+			logger.Error("failed to publish scheduled articles", slog.Any("error", err))
+			schedulerErrors.Inc()
+		}
+		return
+	}
+
+	for _, article := range articles {
+		logger.Info("scheduled article published", slog.Uint64("id", article.ID), slog.String("title", article.Title),
+			slog.Time("publish_at", article.Datetime))
+		scheduledArticlesPublished.Inc()
+	}
+}
@@ -0,0 +1,31 @@
+package news
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulerPublishesDueArticles(t *testing.T) {
+	svc := &stubService{published: []Article{{ID: 5, Title: "Docker Best Practices", Datetime: time.Now()}}}
+	before := testutil.ToFloat64(scheduledArticlesPublished)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NewScheduler(svc, time.Minute).Run(ctx)
+
+	assert.Equal(t, before+1, testutil.ToFloat64(scheduledArticlesPublished))
+}
+
+func TestSchedulerCountsErrors(t *testing.T) {
+	svc := &stubService{err: errors.New("db down")}
+	before := testutil.ToFloat64(schedulerErrors)
+
+	NewScheduler(svc, time.Minute).publishDue(context.Background())
+
+	assert.Equal(t, before+1, testutil.ToFloat64(schedulerErrors))
+}
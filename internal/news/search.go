@@ -0,0 +1,107 @@
+package news
+
+import (
+	"errors"
+	"html"
+	"html/template"
+	"strings"
+)
+
+// ErrUnsupportedLanguage is used when searching with a language that is not indexed.
+var ErrUnsupportedLanguage = errors.New("unsupported search language")
+
+// Delimiters used by ts_headline around matches, replaced by <mark> once the snippet is escaped
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+// Text search configurations indexed by the search_vector column, keyed by the
+// language code accepted by the API. The empty code searches every language.
+type searchConfig struct {
+	query     string
+	highlight string
+	headline  string
+}
+
+var searchConfigs = map[string]searchConfig{
+	"": {
+		query:     "websearch_to_tsquery('english', $1) || websearch_to_tsquery('spanish', $1)",
+		highlight: "websearch_to_tsquery('english', $1) || websearch_to_tsquery('spanish', $1) || websearch_to_tsquery('simple', $1)",
+		headline:  "simple",
+	},
+	"en": {
+		query:     "websearch_to_tsquery('english', $1)",
+		highlight: "websearch_to_tsquery('english', $1)",
+		headline:  "english",
+	},
+	"es": {
+		query:     "websearch_to_tsquery('spanish', $1)",
+		highlight: "websearch_to_tsquery('spanish', $1)",
+		headline:  "spanish",
+	},
+}
+
+// Options received when searching articles
+type SearchOptions struct {
+	Language string
+	Limit    int
+	Offset   int
+}
+
+// SearchResult is an article matching a search along with its rank and highlighted snippet
+type SearchResult struct {
+	Article
+	Rank    float64
+	Snippet template.HTML
+}
+
+// SearchPage of results, NextOffset is zero on the last page. The language and
+// the limit asked for are kept for the link to the next page.
+type SearchPage struct {
+	Query      string
+	Results    []SearchResult
+	NextOffset int
+	Language   string
+	Limit      int
+}
+
+// SearchResultOutput for API responses
+type SearchResultOutput struct {
+	ArticleOutput
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchPageOutput for API responses
+type SearchPageOutput struct {
+	Query      string               `json:"query"`
+	Results    []SearchResultOutput `json:"results"`
+	NextOffset int                  `json:"next_offset,omitempty"`
+}
+
+// Convert SearchPage to SearchPageOutput
+func (p *SearchPage) ToOutput() SearchPageOutput {
+	results := make([]SearchResultOutput, 0, len(p.Results))
+	for i := range p.Results {
+		results = append(results, SearchResultOutput{
+			ArticleOutput: p.Results[i].ToOutput(),
+			Rank:          p.Results[i].Rank,
+			Snippet:       string(p.Results[i].Snippet),
+		})
+	}
+
+	return SearchPageOutput{
+		Query:      p.Query,
+		Results:    results,
+		NextOffset: p.NextOffset,
+	}
+}
+
+// Escape the snippet returned by ts_headline and wrap the matches with <mark>
+func highlightSnippet(snippet string) template.HTML {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	escaped = strings.ReplaceAll(escaped, highlightStop, "</mark>")
+	return template.HTML(escaped) //nolint:gosec // the article text has been escaped above
+}
@@ -2,11 +2,62 @@ package news
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/tracing"
+)
+
+const (
+	// DefaultListLimit is used when the client does not ask for a page size
+	DefaultListLimit = 10
+	// MaxListLimit caps the page size requested by clients
+	MaxListLimit = 50
+	// MaxTitleLength is the maximum number of bytes allowed in an article title
+	MaxTitleLength = 300
+	// MaxBodyLength is the maximum number of bytes allowed in an article body
+	MaxBodyLength = 64 * 1024
+	// MaxTags is the maximum number of tags of an article
+	MaxTags = 20
+	// MaxTagLength is the maximum number of bytes allowed in a tag
+	MaxTagLength = 50
 )
 
+// ErrEmptySearchQuery is used when searching without any search terms.
+var ErrEmptySearchQuery = errors.New("empty search query")
+
+// ErrInvalidArticle is used when the article sent by an editor does not pass validation.
+var ErrInvalidArticle = errors.New("invalid article")
+
+// ErrInvalidStatus is used when filtering articles by a status that does not exist.
+var ErrInvalidStatus = errors.New("invalid status")
+
 type Service interface {
 	GetByID(ctx context.Context, id uint64) (*Article, error)
+	Preview(ctx context.Context, id uint64) (*Article, error)
+	GetBySlug(ctx context.Context, slug string) (*Article, error)
+	PreviewBySlug(ctx context.Context, slug string) (*Article, error)
+	List(ctx context.Context, opts ListOptions) (*Page, error)
+	Create(ctx context.Context, input ArticleInput) (*Article, error)
+	Update(ctx context.Context, id uint64, input ArticleInput) (*Article, error)
+	Patch(ctx context.Context, id uint64, patch ArticlePatch) (*Article, error)
+	Delete(ctx context.Context, id uint64, editor *Author) error
+	Search(ctx context.Context, query string, opts SearchOptions) (*SearchPage, error)
+	Import(ctx context.Context, r RecordReader, opts ImportOptions) (*ImportReport, error)
+	Export(ctx context.Context, w RecordWriter) (int, error)
+	ListTags(ctx context.Context) ([]Tag, error)
+	ListCategories(ctx context.Context) ([]Category, error)
+	PublishScheduled(ctx context.Context) ([]Article, error)
+	ListRevisions(ctx context.Context, id uint64) ([]Revision, error)
+	GetRevision(ctx context.Context, id uint64, number int) (*Revision, error)
+	DiffRevisions(ctx context.Context, id uint64, from int, to int, granularity string) (*RevisionDiff, error)
+	Restore(ctx context.Context, id uint64, number int, editor *Author) (*Article, error)
 }
 
 type service struct {
@@ -20,12 +71,612 @@ func NewService(repo Repository) Service {
 }
 
 func (s *service) GetByID(ctx context.Context, id uint64) (*Article, error) {
-	slog.Debug("service: fetching article", slog.Uint64("id", id))
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.GetByID")
+	defer span.End()
+
+	logger.Debug("service: fetching article", slog.Uint64("id", id))
 	article, err := s.repo.GetByID(ctx, id)
 	if err != nil {
-		slog.Error("service: failed to fetch article", slog.Uint64("id", id), slog.Any("error", err))
+		logger.Error("service: failed to fetch article", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	// Unpublished articles are hidden as if they did not exist
+	if !article.Published() {
+		logger.Warn("service: article not published", slog.Uint64("id", id), slog.String("status", article.Status))
+		return nil, ErrArticleNotFound
+	}
+	logger.Info("service: article fetched successfully", slog.Uint64("id", id))
+	return article, nil
+}
+
+// Preview returns the article whatever its status, only editors may see unpublished articles
+func (s *service) Preview(ctx context.Context, id uint64) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Preview")
+	defer span.End()
+
+	logger.Debug("service: previewing article", slog.Uint64("id", id))
+	article, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("service: failed to fetch article to preview", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: article previewed successfully", slog.Uint64("id", id), slog.String("status", article.Status))
+	return article, nil
+}
+
+// GetBySlug returns the published article with the slug, which may be one of its previous slugs
+func (s *service) GetBySlug(ctx context.Context, slug string) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.GetBySlug")
+	defer span.End()
+
+	logger.Debug("service: fetching article by slug", slog.String("slug", slug))
+	article, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		logger.Error("service: failed to fetch article by slug", slog.String("slug", slug), slog.Any("error", err))
+		return nil, err
+	}
+
+	// Unpublished articles are hidden as if they did not exist
+	if !article.Published() {
+		logger.Warn("service: article not published", slog.String("slug", slug), slog.String("status", article.Status))
+		return nil, ErrArticleNotFound
+	}
+	logger.Info("service: article fetched successfully", slog.Uint64("id", article.ID), slog.String("slug", slug))
+	return article, nil
+}
+
+// PreviewBySlug returns the article with the slug whatever its status, only editors may see unpublished articles
+func (s *service) PreviewBySlug(ctx context.Context, slug string) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.PreviewBySlug")
+	defer span.End()
+
+	logger.Debug("service: previewing article by slug", slog.String("slug", slug))
+	article, err := s.repo.GetBySlug(ctx, slug)
+	if err != nil {
+		logger.Error("service: failed to fetch article to preview by slug", slog.String("slug", slug), slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: article previewed successfully", slog.Uint64("id", article.ID), slog.String("slug", slug), slog.String("status", article.Status))
+	return article, nil
+}
+
+func (s *service) List(ctx context.Context, opts ListOptions) (*Page, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.List")
+	defer span.End()
+
+	limit := clampLimit(opts.Limit)
+	filter := Filter{Tag: normalizeTag(opts.Tag), Category: strings.TrimSpace(opts.Category), AuthorID: opts.AuthorID, Status: StatusPublished}
+	if opts.Preview {
+		filter.Status = opts.Status
+	}
+	logger.Debug("service: listing articles", slog.Int("limit", limit), slog.String("tag", filter.Tag),
+		slog.String("category", filter.Category), slog.String("author_id", filter.AuthorID), slog.String("status", filter.Status))
+
+	if filter.Status != "" && !slices.Contains(statuses, filter.Status) {
+		logger.Warn("service: invalid status", slog.String("status", filter.Status))
+		return nil, fmt.Errorf("%w %q", ErrInvalidStatus, filter.Status)
+	}
+
+	// Decode the cursor sent by the client, if any
+	var after *Cursor
+	if opts.Cursor != "" {
+		cursor, err := DecodeCursor(opts.Cursor)
+		if err != nil {
+			logger.Warn("service: invalid cursor", slog.String("cursor", opts.Cursor))
+			return nil, err
+		}
+		after = cursor
+	}
+
+	// Fetch one extra article to know whether there is a next page
+	articles, err := s.repo.List(ctx, filter, after, limit+1)
+	if err != nil {
+		logger.Error("service: failed to list articles", slog.Any("error", err))
+		return nil, err
+	}
+
+	page := &Page{Articles: articles, Tag: filter.Tag, Category: filter.Category, AuthorID: filter.AuthorID, Preview: opts.Preview,
+		Limit: limit}
+	if opts.Preview {
+		page.Status = filter.Status
+	}
+	if len(articles) > limit {
+		page.Articles = articles[:limit]
+		last := page.Articles[limit-1]
+		page.NextCursor = Cursor{Datetime: last.Datetime, ID: last.ID}.Encode()
+	}
+
+	logger.Info("service: articles listed successfully", slog.Int("count", len(page.Articles)))
+	return page, nil
+}
+
+func (s *service) Create(ctx context.Context, input ArticleInput) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Create")
+	defer span.End()
+
+	logger.Debug("service: creating article", slog.String("title", input.Title))
+
+	// Check the article is valid before storing it
+	if err := validateArticle(&input); err != nil {
+		logger.Warn("service: invalid article", slog.Any("error", err))
+		return nil, err
+	}
+
+	// The tags and the first revision are saved with the article
+	article, err := s.repo.Create(ctx, input)
+	if err != nil {
+		logger.Error("service: failed to create article", slog.String("title", input.Title), slog.Any("error", err))
 		return nil, err
 	}
-	slog.Info("service: article fetched successfully", slog.Uint64("id", id))
+	logger.Info("service: article created successfully", slog.Uint64("id", article.ID))
 	return article, nil
 }
+
+func (s *service) Update(ctx context.Context, id uint64, input ArticleInput) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Update")
+	defer span.End()
+
+	logger.Debug("service: updating article", slog.Uint64("id", id))
+
+	// Check the article is valid before storing it
+	if err := validateArticle(&input); err != nil {
+		logger.Warn("service: invalid article", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	// The tags are replaced like the rest of the article, and every save is kept
+	// as a revision so the previous contents can be compared and restored
+	article, err := s.repo.Update(ctx, id, input)
+	if err != nil {
+		logger.Error("service: failed to update article", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: article updated successfully", slog.Uint64("id", id))
+	return article, nil
+}
+
+func (s *service) Patch(ctx context.Context, id uint64, patch ArticlePatch) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Patch")
+	defer span.End()
+
+	logger.Debug("service: patching article", slog.Uint64("id", id))
+
+	// Get the current article so the fields missing in the patch are kept
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("service: failed to fetch article to patch", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	input := ArticleInput{
+		Title:     current.Title,
+		Body:      current.Body,
+		Category:  current.Category,
+		Tags:      current.Tags,
+		Status:    current.Status,
+		PublishAt: current.PublishAt,
+		Editor:    patch.Editor,
+	}
+	if patch.Title != nil {
+		input.Title = *patch.Title
+	}
+	if patch.Body != nil {
+		input.Body = *patch.Body
+	}
+	if patch.Category != nil {
+		input.Category = *patch.Category
+	}
+	if patch.Tags != nil {
+		input.Tags = *patch.Tags
+	}
+	if patch.Status != nil {
+		input.Status = *patch.Status
+	}
+	if patch.PublishAt != nil {
+		input.PublishAt = patch.PublishAt
+	}
+
+	return s.Update(ctx, id, input)
+}
+
+func (s *service) Delete(ctx context.Context, id uint64, editor *Author) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Delete")
+	defer span.End()
+
+	logger.Debug("service: deleting article", slog.Uint64("id", id))
+	if err := s.repo.Delete(ctx, id, editor); err != nil {
+		logger.Error("service: failed to delete article", slog.Uint64("id", id), slog.Any("error", err))
+		return err
+	}
+	logger.Info("service: article deleted successfully", slog.Uint64("id", id))
+	return nil
+}
+
+func (s *service) Search(ctx context.Context, query string, opts SearchOptions) (*SearchPage, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Search")
+	defer span.End()
+
+	query = strings.TrimSpace(query)
+	limit := clampLimit(opts.Limit)
+	logger.Debug("service: searching articles", slog.String("query", query), slog.Int("limit", limit), slog.Int("offset", opts.Offset))
+
+	if query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+	if _, ok := searchConfigs[opts.Language]; !ok {
+		logger.Warn("service: unsupported search language", slog.String("language", opts.Language))
+		return nil, ErrUnsupportedLanguage
+	}
+
+	offset := max(opts.Offset, 0)
+
+	// Fetch one extra result to know whether there is a next page
+	results, err := s.repo.Search(ctx, query, SearchOptions{Language: opts.Language, Limit: limit + 1, Offset: offset})
+	if err != nil {
+		logger.Error("service: failed to search articles", slog.String("query", query), slog.Any("error", err))
+		return nil, err
+	}
+
+	page := &SearchPage{Query: query, Results: results, Language: opts.Language, Limit: opts.Limit}
+	if len(results) > limit {
+		page.Results = results[:limit]
+		page.NextOffset = offset + limit
+	}
+
+	logger.Info("service: articles searched successfully", slog.String("query", query), slog.Int("count", len(page.Results)))
+	return page, nil
+}
+
+func (s *service) ListTags(ctx context.Context) ([]Tag, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.ListTags")
+	defer span.End()
+
+	tags, err := s.repo.ListTags(ctx)
+	if err != nil {
+		logger.Error("service: failed to list tags", slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: tags listed successfully", slog.Int("count", len(tags)))
+	return tags, nil
+}
+
+func (s *service) ListCategories(ctx context.Context) ([]Category, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.ListCategories")
+	defer span.End()
+
+	categories, err := s.repo.ListCategories(ctx)
+	if err != nil {
+		logger.Error("service: failed to list categories", slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: categories listed successfully", slog.Int("count", len(categories)))
+	return categories, nil
+}
+
+// PublishScheduled publishes the scheduled articles whose publish date has been reached
+func (s *service) PublishScheduled(ctx context.Context) ([]Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.PublishScheduled")
+	defer span.End()
+
+	articles, err := s.repo.PublishDue(ctx, time.Now())
+	if err != nil {
+		logger.Error("service: failed to publish scheduled articles", slog.Any("error", err))
+		return nil, err
+	}
+	logger.Debug("service: scheduled articles published successfully", slog.Int("count", len(articles)))
+	return articles, nil
+}
+
+// ListRevisions returns the revisions of an article whatever its status, the newest first
+func (s *service) ListRevisions(ctx context.Context, id uint64) ([]Revision, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.ListRevisions")
+	defer span.End()
+
+	logger.Debug("service: listing revisions", slog.Uint64("id", id))
+
+	revisions, err := s.repo.ListRevisions(ctx, id)
+	if err != nil {
+		logger.Error("service: failed to list revisions", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	// Deleted articles keep their revisions, an id without any is not an article
+	if len(revisions) == 0 {
+		logger.Warn("service: article not found to list its revisions", slog.Uint64("id", id))
+		return nil, ErrArticleNotFound
+	}
+	logger.Info("service: revisions listed successfully", slog.Uint64("id", id), slog.Int("count", len(revisions)))
+	return revisions, nil
+}
+
+func (s *service) GetRevision(ctx context.Context, id uint64, number int) (*Revision, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.GetRevision")
+	defer span.End()
+
+	logger.Debug("service: fetching revision", slog.Uint64("id", id), slog.Int("revision", number))
+	revision, err := s.repo.GetRevision(ctx, id, number)
+	if err != nil {
+		logger.Error("service: failed to fetch revision", slog.Uint64("id", id), slog.Int("revision", number), slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: revision fetched successfully", slog.Uint64("id", id), slog.Int("revision", number))
+	return revision, nil
+}
+
+// DiffRevisions compares two revisions of an article line by line or word by word
+func (s *service) DiffRevisions(ctx context.Context, id uint64, from int, to int, granularity string) (*RevisionDiff, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.DiffRevisions")
+	defer span.End()
+
+	if granularity == "" {
+		granularity = DiffLines
+	}
+	logger.Debug("service: diffing revisions", slog.Uint64("id", id), slog.Int("from", from), slog.Int("to", to), slog.String("mode", granularity))
+
+	if granularity != DiffLines && granularity != DiffWords {
+		logger.Warn("service: invalid diff mode", slog.String("mode", granularity))
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidDiff, DiffLines, DiffWords)
+	}
+
+	fromRevision, err := s.repo.GetRevision(ctx, id, from)
+	if err != nil {
+		logger.Error("service: failed to fetch revision", slog.Uint64("id", id), slog.Int("revision", from), slog.Any("error", err))
+		return nil, err
+	}
+	toRevision, err := s.repo.GetRevision(ctx, id, to)
+	if err != nil {
+		logger.Error("service: failed to fetch revision", slog.Uint64("id", id), slog.Int("revision", to), slog.Any("error", err))
+		return nil, err
+	}
+
+	diff := newRevisionDiff(fromRevision, toRevision, granularity)
+	logger.Info("service: revisions diffed successfully", slog.Uint64("id", id), slog.Int("from", from), slog.Int("to", to))
+	return diff, nil
+}
+
+// Restore saves the content of an old revision as the current one, which
+// records a new revision. The status and publish date are kept.
+func (s *service) Restore(ctx context.Context, id uint64, number int, editor *Author) (*Article, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Restore")
+	defer span.End()
+
+	logger.Debug("service: restoring revision", slog.Uint64("id", id), slog.Int("revision", number))
+
+	revision, err := s.repo.GetRevision(ctx, id, number)
+	if err != nil {
+		logger.Error("service: failed to fetch revision to restore", slog.Uint64("id", id), slog.Int("revision", number), slog.Any("error", err))
+		return nil, err
+	}
+	current, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		logger.Error("service: failed to fetch article to restore", slog.Uint64("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	article, err := s.Update(ctx, id, ArticleInput{
+		Title:     revision.Title,
+		Body:      revision.Body,
+		Category:  revision.Category,
+		Tags:      revision.Tags,
+		Status:    current.Status,
+		PublishAt: current.PublishAt,
+		Editor:    editor,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("service: revision restored successfully", slog.Uint64("id", id), slog.Int("revision", number))
+	return article, nil
+}
+
+// Import upserts the records of r in batches, each one in its own transaction.
+// Invalid records are skipped and reported, nothing is written on dry runs.
+func (s *service) Import(ctx context.Context, r RecordReader, opts ImportOptions) (*ImportReport, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Import")
+	defer span.End()
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultImportBatchSize
+	}
+	logger.Debug("service: importing articles", slog.Bool("dry_run", opts.DryRun), slog.Int("batch_size", batchSize))
+
+	report := &ImportReport{DryRun: opts.DryRun, Errors: []LineError{}}
+	batch := make([]Record, 0, batchSize)
+	firstLine := 0
+
+	// Write the pending records, the lines are reported when the batch fails
+	flush := func(lastLine int) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		inserted, updated, err := s.repo.UpsertBatch(ctx, batch)
+		if err != nil {
+			return fmt.Errorf("failed to import lines %d-%d: %w", firstLine, lastLine, err)
+		}
+		report.Inserted += inserted
+		report.Updated += updated
+		batch = batch[:0]
+		return nil
+	}
+
+	line := 0
+	for {
+		record, recordLine, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, ErrMalformedRecord) {
+			report.Processed++
+			report.addError(recordLine, err)
+			continue
+		}
+		if err != nil {
+			logger.Error("service: failed to read import file", slog.Any("error", err))
+			return report, err
+		}
+
+		report.Processed++
+		line = recordLine
+		if err := validateRecord(&record); err != nil {
+			report.addError(line, err)
+			continue
+		}
+		report.Valid++
+		if opts.DryRun {
+			continue
+		}
+
+		if len(batch) == 0 {
+			firstLine = line
+		}
+		batch = append(batch, record)
+		if len(batch) == batchSize {
+			if err := flush(line); err != nil {
+				logger.Error("service: failed to import articles", slog.Any("error", err))
+				return report, err
+			}
+		}
+	}
+	if err := flush(line); err != nil {
+		logger.Error("service: failed to import articles", slog.Any("error", err))
+		return report, err
+	}
+
+	logger.Info("service: articles imported successfully", slog.Bool("dry_run", opts.DryRun), slog.Int("inserted", report.Inserted),
+		slog.Int("updated", report.Updated), slog.Int("invalid", report.Invalid))
+	return report, nil
+}
+
+// Export writes every article to w and returns how many were written
+func (s *service) Export(ctx context.Context, w RecordWriter) (int, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "news.Service.Export")
+	defer span.End()
+
+	count := 0
+	err := s.repo.Export(ctx, func(record Record) error {
+		count++
+		return w.Write(record)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		logger.Error("service: failed to export articles", slog.Any("error", err))
+		return count, err
+	}
+
+	logger.Info("service: articles exported successfully", slog.Int("count", count))
+	return count, nil
+}
+
+// Normalize and validate the article sent by an editor
+func validateArticle(input *ArticleInput) error {
+	input.Title = strings.TrimSpace(input.Title)
+
+	if input.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidArticle)
+	}
+	if len(input.Title) > MaxTitleLength {
+		return fmt.Errorf("%w: title must be at most %d bytes", ErrInvalidArticle, MaxTitleLength)
+	}
+	if len(input.Body) > MaxBodyLength {
+		return fmt.Errorf("%w: body must be at most %d bytes", ErrInvalidArticle, MaxBodyLength)
+	}
+
+	input.Category = strings.TrimSpace(input.Category)
+
+	// Tags are compared case-insensitively, so they are stored in lower case and without duplicates
+	var tags []string
+	for _, tag := range input.Tags {
+		tag = normalizeTag(tag)
+		if tag == "" {
+			return fmt.Errorf("%w: tags must not be empty", ErrInvalidArticle)
+		}
+		if len(tag) > MaxTagLength {
+			return fmt.Errorf("%w: tags must be at most %d bytes", ErrInvalidArticle, MaxTagLength)
+		}
+		// Commas separate the tags of CSV files
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("%w: tags must not contain commas", ErrInvalidArticle)
+		}
+		if !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidArticle, MaxTags)
+	}
+	input.Tags = tags
+
+	if input.Status == "" {
+		input.Status = StatusPublished
+		if input.PublishAt != nil {
+			input.Status = StatusScheduled
+		}
+	}
+	if !slices.Contains(statuses, input.Status) {
+		return fmt.Errorf("%w: status must be one of: %s", ErrInvalidArticle, strings.Join(statuses, ", "))
+	}
+	// The publish date only matters while the article is scheduled
+	if input.Status == StatusScheduled && input.PublishAt == nil {
+		return fmt.Errorf("%w: publish_at is required for scheduled articles", ErrInvalidArticle)
+	}
+	if input.Status != StatusScheduled {
+		input.PublishAt = nil
+	}
+	return nil
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultListLimit
+	}
+	if limit > MaxListLimit {
+		return MaxListLimit
+	}
+	return limit
+}
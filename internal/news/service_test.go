@@ -3,17 +3,37 @@ package news
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 type stubRepository struct {
-	article *Article
-	err     error
-	called  bool
-	lastID  uint64
+	article    *Article
+	articles   []Article
+	err        error
+	called     bool
+	lastID     uint64
+	lastAfter  *Cursor
+	lastLimit  int
+	lastInput  ArticleInput
+	results    []SearchResult
+	lastQuery  string
+	lastOpts   SearchOptions
+	batches    [][]Record
+	exported   []Record
+	lastTags   []string
+	tags       []Tag
+	lastFilter Filter
+	due        []Article
+	lastNow    time.Time
+	revisions  map[int]*Revision
+	editors    []*Author
 }
 
 func (s *stubRepository) GetByID(_ context.Context, id uint64) (*Article, error) {
@@ -22,8 +42,110 @@ func (s *stubRepository) GetByID(_ context.Context, id uint64) (*Article, error)
 	return s.article, s.err
 }
 
+func (s *stubRepository) GetBySlug(_ context.Context, _ string) (*Article, error) {
+	s.called = true
+	return s.article, s.err
+}
+
+func (s *stubRepository) List(_ context.Context, filter Filter, after *Cursor, limit int) ([]Article, error) {
+	s.called = true
+	s.lastFilter = filter
+	s.lastAfter = after
+	s.lastLimit = limit
+	return s.articles, s.err
+}
+
+func (s *stubRepository) Create(_ context.Context, input ArticleInput) (*Article, error) {
+	s.called = true
+	s.save(input)
+	return s.article, s.err
+}
+
+func (s *stubRepository) Update(_ context.Context, id uint64, input ArticleInput) (*Article, error) {
+	s.called = true
+	s.lastID = id
+	s.save(input)
+	return s.article, s.err
+}
+
+// Like the repository, the tags and the revision are saved with the article
+func (s *stubRepository) save(input ArticleInput) {
+	s.lastInput = input
+	s.lastTags = input.Tags
+	s.editors = append(s.editors, input.Editor)
+	if s.article != nil {
+		s.article.Tags = input.Tags
+	}
+}
+
+func (s *stubRepository) Delete(_ context.Context, id uint64, editor *Author) error {
+	s.called = true
+	s.lastID = id
+	s.editors = append(s.editors, editor)
+	return s.err
+}
+
+func (s *stubRepository) Search(_ context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	s.called = true
+	s.lastQuery = query
+	s.lastOpts = opts
+	return s.results, s.err
+}
+
+func (s *stubRepository) UpsertBatch(_ context.Context, records []Record) (int, int, error) {
+	s.called = true
+	s.batches = append(s.batches, slices.Clone(records))
+	return len(records), 0, s.err
+}
+
+func (s *stubRepository) Export(_ context.Context, fn func(record Record) error) error {
+	s.called = true
+	for _, record := range s.exported {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return s.err
+}
+
+func (s *stubRepository) ListTags(_ context.Context) ([]Tag, error) {
+	s.called = true
+	return s.tags, s.err
+}
+
+func (s *stubRepository) ListCategories(_ context.Context) ([]Category, error) {
+	s.called = true
+	return nil, s.err
+}
+
+func (s *stubRepository) PublishDue(_ context.Context, now time.Time) ([]Article, error) {
+	s.called = true
+	s.lastNow = now
+	return s.due, s.err
+}
+
+func (s *stubRepository) ListRevisions(_ context.Context, id uint64) ([]Revision, error) {
+	s.called = true
+	s.lastID = id
+	var revisions []Revision
+	for _, revision := range s.revisions {
+		revisions = append(revisions, *revision)
+	}
+	return revisions, s.err
+}
+
+func (s *stubRepository) GetRevision(_ context.Context, id uint64, number int) (*Revision, error) {
+	s.called = true
+	s.lastID = id
+	revision, ok := s.revisions[number]
+	if !ok {
+		return nil, ErrRevisionNotFound
+	}
+	return revision, s.err
+}
+
 func TestServiceGetByIDSuccess(t *testing.T) {
-	article := &Article{Title: "fake_title", Body: "fake_body", Datetime: time.Now()}
+	article := &Article{Title: "fake_title", Body: "fake_body", Datetime: time.Now(), Status: StatusPublished}
 	repo := &stubRepository{article: article}
 
 	svc := NewService(repo)
@@ -44,3 +166,471 @@ func TestServiceGetByIDError(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, repo.called)
 }
+
+func TestServiceGetBySlugHidesUnpublished(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 1, Slug: "fake-title", Status: StatusDraft}}
+	svc := NewService(repo)
+
+	_, err := svc.GetBySlug(context.Background(), "fake-title")
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+
+	article, err := svc.PreviewBySlug(context.Background(), "fake-title")
+	assert.NoError(t, err)
+	assert.Equal(t, "fake-title", article.Slug)
+}
+
+func TestServiceGetByIDHidesUnpublished(t *testing.T) {
+	for _, status := range []string{StatusDraft, StatusScheduled, StatusArchived} {
+		repo := &stubRepository{article: &Article{ID: 1, Status: status}}
+		svc := NewService(repo)
+
+		_, err := svc.GetByID(context.Background(), 1)
+		assert.ErrorIs(t, err, ErrArticleNotFound, status)
+
+		article, err := svc.Preview(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, status, article.Status)
+	}
+}
+
+func TestServiceListFirstPage(t *testing.T) {
+	now := time.Now()
+	repo := &stubRepository{articles: []Article{
+		{ID: 3, Datetime: now},
+		{ID: 2, Datetime: now.Add(-time.Hour)},
+		{ID: 1, Datetime: now.Add(-2 * time.Hour)},
+	}}
+	svc := NewService(repo)
+
+	page, err := svc.List(context.Background(), ListOptions{Limit: 2})
+
+	assert.NoError(t, err)
+	assert.Nil(t, repo.lastAfter)
+	assert.Equal(t, 3, repo.lastLimit)
+	assert.Len(t, page.Articles, 2)
+	assert.Equal(t, 2, page.Limit)
+
+	cursor, err := DecodeCursor(page.NextCursor)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cursor.ID)
+	assert.True(t, now.Add(-time.Hour).Equal(cursor.Datetime))
+}
+
+func TestServiceListLastPage(t *testing.T) {
+	repo := &stubRepository{articles: []Article{{ID: 1, Datetime: time.Now()}}}
+	svc := NewService(repo)
+
+	after := Cursor{Datetime: time.Now(), ID: 2}
+	page, err := svc.List(context.Background(), ListOptions{Cursor: after.Encode()})
+
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultListLimit+1, repo.lastLimit)
+	assert.Equal(t, uint64(2), repo.lastAfter.ID)
+	assert.Len(t, page.Articles, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestServiceListCapsLimit(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	_, err := svc.List(context.Background(), ListOptions{Limit: 1000})
+
+	assert.NoError(t, err)
+	assert.Equal(t, MaxListLimit+1, repo.lastLimit)
+}
+
+func TestServiceListFilters(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	page, err := svc.List(context.Background(), ListOptions{Tag: " Security ", Category: " tech", AuthorID: "64b7f0c2a1b2c3d4e5f60718"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, Filter{Tag: "security", Category: "tech", AuthorID: "64b7f0c2a1b2c3d4e5f60718", Status: StatusPublished}, repo.lastFilter)
+	assert.Equal(t, "64b7f0c2a1b2c3d4e5f60718", page.AuthorID)
+	assert.Equal(t, "security", page.Tag)
+	assert.Equal(t, "tech", page.Category)
+}
+
+func TestServiceListPreview(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	page, err := svc.List(context.Background(), ListOptions{Preview: true, Status: StatusDraft})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusDraft, repo.lastFilter.Status)
+	assert.True(t, page.Preview)
+
+	_, err = svc.List(context.Background(), ListOptions{Preview: true})
+	assert.NoError(t, err)
+	assert.Empty(t, repo.lastFilter.Status)
+
+	// The status is only honored in preview mode
+	_, err = svc.List(context.Background(), ListOptions{Status: StatusDraft})
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPublished, repo.lastFilter.Status)
+
+	_, err = svc.List(context.Background(), ListOptions{Preview: true, Status: "deleted"})
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestServiceListInvalidCursor(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	_, err := svc.List(context.Background(), ListOptions{Cursor: "not-a-cursor"})
+
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	assert.False(t, repo.called)
+}
+
+func TestServiceCreateTrimsTitle(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 1}}
+	svc := NewService(repo)
+
+	_, err := svc.Create(context.Background(), ArticleInput{Title: "  fake_title  ", Body: "fake_body"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, ArticleInput{Title: "fake_title", Body: "fake_body", Status: StatusPublished}, repo.lastInput)
+}
+
+func TestServiceCreateNormalizesTags(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 4}}
+	svc := NewService(repo)
+
+	article, err := svc.Create(context.Background(), ArticleInput{Title: "fake_title", Category: " tech ", Tags: []string{" Go", "security", "go"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "tech", repo.lastInput.Category)
+	assert.Equal(t, []string{"go", "security"}, repo.lastTags)
+	assert.Equal(t, []string{"go", "security"}, article.Tags)
+}
+
+func TestServiceCreateValidation(t *testing.T) {
+	tooManyTags := make([]string, MaxTags+1)
+	for i := range tooManyTags {
+		tooManyTags[i] = fmt.Sprintf("tag%d", i)
+	}
+
+	cases := []ArticleInput{
+		{Title: "   "},
+		{Title: strings.Repeat("t", MaxTitleLength+1)},
+		{Title: "fake_title", Body: strings.Repeat("b", MaxBodyLength+1)},
+		{Title: "fake_title", Tags: []string{" "}},
+		{Title: "fake_title", Tags: []string{strings.Repeat("t", MaxTagLength+1)}},
+		{Title: "fake_title", Tags: []string{"go,security"}},
+		{Title: "fake_title", Tags: tooManyTags},
+		{Title: "fake_title", Status: "deleted"},
+		{Title: "fake_title", Status: StatusScheduled},
+	}
+
+	for _, input := range cases {
+		repo := &stubRepository{}
+		svc := NewService(repo)
+
+		_, err := svc.Create(context.Background(), input)
+
+		assert.ErrorIs(t, err, ErrInvalidArticle)
+		assert.False(t, repo.called)
+	}
+}
+
+func TestServiceCreateStatus(t *testing.T) {
+	publishAt := time.Now().Add(time.Hour)
+	cases := []struct {
+		input     ArticleInput
+		status    string
+		publishAt *time.Time
+	}{
+		{ArticleInput{Title: "fake_title"}, StatusPublished, nil},
+		{ArticleInput{Title: "fake_title", PublishAt: &publishAt}, StatusScheduled, &publishAt},
+		{ArticleInput{Title: "fake_title", Status: StatusDraft, PublishAt: &publishAt}, StatusDraft, nil},
+	}
+
+	for _, tc := range cases {
+		repo := &stubRepository{article: &Article{ID: 1}}
+		svc := NewService(repo)
+
+		_, err := svc.Create(context.Background(), tc.input)
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.status, repo.lastInput.Status)
+		assert.Equal(t, tc.publishAt, repo.lastInput.PublishAt)
+	}
+}
+
+func TestServiceUpdateValidation(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	_, err := svc.Update(context.Background(), 1, ArticleInput{})
+
+	assert.ErrorIs(t, err, ErrInvalidArticle)
+	assert.False(t, repo.called)
+}
+
+func TestServicePatchKeepsMissingFields(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 5, Title: "old_title", Body: "old_body", Category: "tech", Tags: []string{"go"}, Status: StatusDraft}}
+	svc := NewService(repo)
+
+	title := "new_title"
+	_, err := svc.Patch(context.Background(), 5, ArticlePatch{Title: &title})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), repo.lastID)
+	assert.Equal(t, ArticleInput{Title: "new_title", Body: "old_body", Category: "tech", Tags: []string{"go"}, Status: StatusDraft}, repo.lastInput)
+	assert.Equal(t, []string{"go"}, repo.lastTags)
+}
+
+func TestServicePatchTags(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 5, Title: "old_title", Tags: []string{"go"}}}
+	svc := NewService(repo)
+
+	tags := []string{}
+	_, err := svc.Patch(context.Background(), 5, ArticlePatch{Tags: &tags})
+
+	assert.NoError(t, err)
+	assert.Empty(t, repo.lastTags)
+}
+
+func TestServicePatchPublishesDraft(t *testing.T) {
+	repo := &stubRepository{article: &Article{ID: 5, Title: "old_title", Status: StatusDraft}}
+	svc := NewService(repo)
+
+	status := StatusPublished
+	_, err := svc.Patch(context.Background(), 5, ArticlePatch{Status: &status})
+
+	assert.NoError(t, err)
+	assert.Equal(t, StatusPublished, repo.lastInput.Status)
+}
+
+func TestServicePatchNotFound(t *testing.T) {
+	repo := &stubRepository{err: ErrArticleNotFound}
+	svc := NewService(repo)
+
+	_, err := svc.Patch(context.Background(), 5, ArticlePatch{})
+
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+}
+
+func TestServiceSavesRecordRevisions(t *testing.T) {
+	editor := &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}
+	repo := &stubRepository{article: &Article{ID: 5, Title: "old_title", Status: StatusPublished}}
+	svc := NewService(repo)
+
+	_, err := svc.Create(context.Background(), ArticleInput{Title: "fake_title", Editor: editor})
+	assert.NoError(t, err)
+	_, err = svc.Update(context.Background(), 5, ArticleInput{Title: "fake_title", Editor: editor})
+	assert.NoError(t, err)
+	title := "new_title"
+	_, err = svc.Patch(context.Background(), 5, ArticlePatch{Title: &title, Editor: editor})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []*Author{editor, editor, editor}, repo.editors)
+}
+
+func TestServiceListRevisionsNotFound(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	_, err := svc.ListRevisions(context.Background(), 5)
+
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+}
+
+func TestServiceListRevisionsOfDeletedArticle(t *testing.T) {
+	repo := &stubRepository{revisions: map[int]*Revision{
+		1: {ArticleID: 5, Number: 1, Title: "Go 1.26 Released", Status: RevisionDeleted},
+	}}
+	svc := NewService(repo)
+
+	revisions, err := svc.ListRevisions(context.Background(), 5)
+
+	assert.NoError(t, err)
+	require.Len(t, revisions, 1)
+	assert.Equal(t, RevisionDeleted, revisions[0].Status)
+}
+
+func TestServiceDiffRevisions(t *testing.T) {
+	repo := &stubRepository{revisions: map[int]*Revision{
+		1: {ArticleID: 5, Number: 1, Title: "Go 1.25 Released", Body: "first line\nsecond line\n", Tags: []string{"go"}},
+		2: {ArticleID: 5, Number: 2, Title: "Go 1.26 Released", Body: "first line\nchanged line\n", Tags: []string{"go", "release"}},
+	}}
+	svc := NewService(repo)
+
+	diff, err := svc.DiffRevisions(context.Background(), 5, 1, 2, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, DiffLines, diff.Granularity)
+	assert.True(t, diff.Changed())
+	assert.Equal(t, []DiffOp{{DiffEqual, "Go "}, {DiffDelete, "1.25"}, {DiffInsert, "1.26"}, {DiffEqual, " Released"}}, diff.Title)
+	assert.Equal(t, []DiffOp{{DiffEqual, "first line\n"}, {DiffDelete, "second line\n"}, {DiffInsert, "changed line\n"}}, diff.Body)
+	assert.Equal(t, []DiffOp{{DiffEqual, "go\n"}, {DiffInsert, "release\n"}}, diff.Tags)
+
+	_, err = svc.DiffRevisions(context.Background(), 5, 1, 2, "char")
+	assert.ErrorIs(t, err, ErrInvalidDiff)
+
+	_, err = svc.DiffRevisions(context.Background(), 5, 1, 3, DiffWords)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestServiceRestore(t *testing.T) {
+	editor := &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}
+	publishAt := time.Now().Add(time.Hour)
+	repo := &stubRepository{
+		article:   &Article{ID: 5, Title: "new_title", Status: StatusScheduled, PublishAt: &publishAt},
+		revisions: map[int]*Revision{1: {ArticleID: 5, Number: 1, Title: "old_title", Body: "old_body", Category: "tech", Tags: []string{"go"}}},
+	}
+	svc := NewService(repo)
+
+	_, err := svc.Restore(context.Background(), 5, 1, editor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, ArticleInput{Title: "old_title", Body: "old_body", Category: "tech", Tags: []string{"go"}, Status: StatusScheduled, PublishAt: &publishAt, Editor: editor},
+		repo.lastInput)
+	assert.Equal(t, []*Author{editor}, repo.editors)
+
+	_, err = svc.Restore(context.Background(), 5, 2, editor)
+	assert.ErrorIs(t, err, ErrRevisionNotFound)
+}
+
+func TestServiceDelete(t *testing.T) {
+	editor := &Author{ID: "64b7f0c2a1b2c3d4e5f60718", Name: "alice"}
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	err := svc.Delete(context.Background(), 9, editor)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(9), repo.lastID)
+	assert.Equal(t, []*Author{editor}, repo.editors)
+}
+
+func TestServiceSearchPaginates(t *testing.T) {
+	repo := &stubRepository{results: []SearchResult{{Article: Article{ID: 1}}, {Article: Article{ID: 2}}, {Article: Article{ID: 3}}}}
+	svc := NewService(repo)
+
+	page, err := svc.Search(context.Background(), "  ransomware ", SearchOptions{Language: "es", Limit: 2, Offset: 4})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ransomware", repo.lastQuery)
+	assert.Equal(t, SearchOptions{Language: "es", Limit: 3, Offset: 4}, repo.lastOpts)
+	assert.Len(t, page.Results, 2)
+	assert.Equal(t, 6, page.NextOffset)
+	assert.Equal(t, "es", page.Language)
+	assert.Equal(t, 2, page.Limit)
+}
+
+func TestServiceSearchLastPage(t *testing.T) {
+	repo := &stubRepository{results: []SearchResult{{Article: Article{ID: 1}}}}
+	svc := NewService(repo)
+
+	page, err := svc.Search(context.Background(), "go", SearchOptions{Offset: -5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, repo.lastOpts.Offset)
+	assert.Equal(t, 0, page.NextOffset)
+}
+
+func TestServiceSearchValidation(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	_, err := svc.Search(context.Background(), "   ", SearchOptions{})
+	assert.ErrorIs(t, err, ErrEmptySearchQuery)
+
+	_, err = svc.Search(context.Background(), "go", SearchOptions{Language: "fr"})
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+
+	assert.False(t, repo.called)
+}
+
+func TestServiceImportInBatches(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	input := `{"external_id": "wp-1", "title": " First "}
+{"external_id": "wp-2", "title": "Second"}
+
+{"external_id": "wp-3", "title": ""}
+not json
+{"external_id": "wp-4", "title": "Fourth", "datetime": "2024-05-01T10:00:00Z"}
+`
+	reader, err := NewRecordReader(FormatJSONL, strings.NewReader(input))
+	assert.NoError(t, err)
+
+	report, err := svc.Import(context.Background(), reader, ImportOptions{BatchSize: 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Processed)
+	assert.Equal(t, 3, report.Valid)
+	assert.Equal(t, 3, report.Inserted)
+	assert.Equal(t, 2, report.Invalid)
+	assert.Equal(t, 4, report.Errors[0].Line)
+	assert.Contains(t, report.Errors[0].Error, "title is required")
+	assert.Equal(t, 5, report.Errors[1].Line)
+
+	assert.Len(t, repo.batches, 2)
+	assert.Equal(t, "First", repo.batches[0][0].Title)
+	assert.Equal(t, "wp-4", repo.batches[1][0].ExternalID)
+	assert.Equal(t, time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), repo.batches[1][0].Datetime)
+}
+
+func TestServiceImportDryRun(t *testing.T) {
+	repo := &stubRepository{}
+	svc := NewService(repo)
+
+	reader, err := NewRecordReader(FormatCSV, strings.NewReader("external_id,title\n,No id\nwp-1,Valid\n"))
+	assert.NoError(t, err)
+
+	report, err := svc.Import(context.Background(), reader, ImportOptions{DryRun: true})
+
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Valid)
+	assert.Equal(t, 0, report.Inserted)
+	assert.Equal(t, []LineError{{Line: 2, Error: "invalid article: external_id is required"}}, report.Errors)
+	assert.False(t, repo.called)
+}
+
+func TestServiceImportBatchError(t *testing.T) {
+	repo := &stubRepository{err: ErrArticleAlreadyExists}
+	svc := NewService(repo)
+
+	reader, err := NewRecordReader(FormatJSONL, strings.NewReader(`{"external_id": "wp-1", "title": "First"}`))
+	assert.NoError(t, err)
+
+	_, err = svc.Import(context.Background(), reader, ImportOptions{})
+
+	assert.ErrorIs(t, err, ErrArticleAlreadyExists)
+	assert.ErrorContains(t, err, "lines 1-1")
+}
+
+func TestServiceExport(t *testing.T) {
+	repo := &stubRepository{exported: []Record{{ID: 1, Title: "First"}, {ID: 2, ExternalID: "wp-2", Title: "Second"}}}
+	svc := NewService(repo)
+
+	var out strings.Builder
+	writer, err := NewRecordWriter(FormatJSONL, &out)
+	assert.NoError(t, err)
+
+	count, err := svc.Export(context.Background(), writer)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, `{"id":1,"external_id":"","title":"First","body":""}
+{"id":2,"external_id":"wp-2","title":"Second","body":""}
+`, out.String())
+}
+
+func TestServicePublishScheduled(t *testing.T) {
+	repo := &stubRepository{due: []Article{{ID: 5, Status: StatusPublished}}}
+	svc := NewService(repo)
+
+	articles, err := svc.PublishScheduled(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, articles, 1)
+	assert.WithinDuration(t, time.Now(), repo.lastNow, time.Second)
+}
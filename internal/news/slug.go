@@ -0,0 +1,75 @@
+package news
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxSlugLength is the maximum number of bytes of a slug, before the suffix that makes it unique
+const MaxSlugLength = 80
+
+// Slug of the articles whose title has no letters or digits
+const defaultSlug = "article"
+
+// Slugs that would be shadowed by the other /news routes
+var reservedSlugs = []string{"list", "search"}
+
+// Letters that are not decomposed into a base letter and an accent
+var slugReplacements = map[rune]string{
+	'ß': "ss",
+	'æ': "ae",
+	'œ': "oe",
+	'ø': "o",
+	'ł': "l",
+	'đ': "d",
+	'þ': "th",
+}
+
+// Build the slug of a title: accents are removed ("alertó" becomes "alerto"),
+// letters are lowercased and the rest of the characters become dashes. Letters
+// of other scripts are kept, so the slug may need escaping in URLs.
+func slugify(title string) string {
+	var b strings.Builder
+	dash := false
+	write := func(s string) {
+		// Runs of other characters become a single dash between words
+		if dash && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		dash = false
+		b.WriteString(s)
+	}
+
+	for _, r := range norm.NFD.String(title) {
+		r = unicode.ToLower(r)
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Accents are dropped, the base letter has already been written
+		case slugReplacements[r] != "":
+			write(slugReplacements[r])
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			write(string(r))
+		default:
+			dash = true
+		}
+	}
+	return finishSlug(b.String())
+}
+
+// Cap the length of the slug without splitting a character and without trailing dashes
+func finishSlug(slug string) string {
+	if len(slug) > MaxSlugLength {
+		slug = slug[:MaxSlugLength]
+		for !utf8.ValidString(slug) {
+			slug = slug[:len(slug)-1]
+		}
+	}
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		return defaultSlug
+	}
+	return slug
+}
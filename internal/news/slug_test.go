@@ -0,0 +1,47 @@
+package news
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Wannacry: el ransomware que alertó a todo el mundo":      "wannacry-el-ransomware-que-alerto-a-todo-el-mundo",
+		"Listado de empresas afectadas por vulnerabilidades SQLi": "listado-de-empresas-afectadas-por-vulnerabilidades-sqli",
+		"Go 1.25 Released":                "go-1-25-released",
+		"¿Qué pasó con la señal del año?": "que-paso-con-la-senal-del-ano",
+		"Straße & Œuvre — Ærø":            "strasse-oeuvre-aero",
+		"  --Docker   Best_Practices!! ":  "docker-best-practices",
+		"Новости дня":                     "новости-дня",
+		"¡¿?!":                            defaultSlug,
+	}
+
+	for title, slug := range cases {
+		assert.Equal(t, slug, slugify(title), title)
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	slug := slugify(strings.Repeat("ñandú ", 30))
+
+	assert.LessOrEqual(t, len(slug), MaxSlugLength)
+	assert.True(t, strings.HasPrefix(slug, "nandu-nandu"))
+	assert.False(t, strings.HasSuffix(slug, "-"))
+
+	// Characters of more than one byte are never split
+	slug = slugify(strings.Repeat("я", MaxSlugLength))
+	assert.True(t, utf8.ValidString(slug))
+	assert.LessOrEqual(t, len(slug), MaxSlugLength)
+}
+
+func TestSlugFrom(t *testing.T) {
+	assert.True(t, slugFrom("go-1-25-released-3", "go-1-25-released"))
+	assert.False(t, slugFrom("go-1-25-released-rc", "go-1-25-released"))
+	assert.False(t, slugFrom("go-1-25-released-", "go-1-25-released"))
+	assert.False(t, slugFrom("go-1-25-released", "go-1-25-released"))
+	assert.False(t, slugFrom("", "go-1-25-released"))
+}
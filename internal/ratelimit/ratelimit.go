@@ -0,0 +1,139 @@
+package ratelimit
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Result of asking the limiter to let a request through
+type Result struct {
+	Allowed bool
+	// Maximum number of requests in a burst
+	Limit int
+	// Requests left before being throttled
+	Remaining int
+	// Time until the quota is fully restored
+	Reset time.Duration
+	// Time until the next request is allowed, only set when Allowed is false
+	RetryAfter time.Duration
+}
+
+// Limiter decides whether a request identified by key can go through
+type Limiter interface {
+	Allow(key string) Result
+}
+
+// KeyFunc identifies the client sending the request
+type KeyFunc func(c *gin.Context) string
+
+// KeyByClientIP throttles requests by client IP
+func KeyByClientIP(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// Middleware throttles the requests of each client and sends the RateLimit-*
+// headers, requests over the limit are rejected with 429 and Retry-After.
+func Middleware(limiter Limiter, key KeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := limiter.Allow(key(c))
+
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+
+		if !result.Allowed {
+			slog.Warn("rate limit exceeded", slog.String("client_ip", c.ClientIP()), slog.String("path", c.FullPath()))
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Buckets idle for this long are full again and can be forgotten
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type tokenBucket struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	capacity  float64
+	perSecond float64
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewTokenBucket allows bursts of up to requests and refills the bucket of
+// every key at a rate of requests per period. Buckets are kept in memory.
+func NewTokenBucket(requests int, period time.Duration) Limiter {
+	return &tokenBucket{
+		buckets:   make(map[string]*bucket),
+		capacity:  float64(requests),
+		perSecond: float64(requests) / period.Seconds(),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (l *tokenBucket) Allow(key string) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	// Refill the tokens earned since the last request
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.capacity, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.capacity, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+
+	result := Result{Limit: int(l.capacity)}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.timeToEarn(1 - b.tokens)
+	}
+
+	result.Remaining = int(math.Floor(b.tokens))
+	result.Reset = l.timeToEarn(l.capacity - b.tokens)
+	return result
+}
+
+// Forget the buckets that have been refilled completely, callers must hold the lock
+func (l *tokenBucket) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func (l *tokenBucket) timeToEarn(tokens float64) time.Duration {
+	return time.Duration(tokens / l.perSecond * float64(time.Second))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
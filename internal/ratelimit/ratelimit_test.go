@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestBucket(requests int, period time.Duration, now *time.Time) *tokenBucket {
+	limiter := NewTokenBucket(requests, period).(*tokenBucket)
+	limiter.now = func() time.Time { return *now }
+	limiter.lastSweep = *now
+	return limiter
+}
+
+func TestTokenBucketAllowsBurstThenThrottles(t *testing.T) {
+	now := time.Now()
+	limiter := newTestBucket(3, time.Minute, &now)
+
+	for i := 2; i >= 0; i-- {
+		result := limiter.Allow("client")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3, result.Limit)
+		assert.Equal(t, i, result.Remaining)
+	}
+
+	result := limiter.Allow("client")
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 20*time.Second, result.RetryAfter)
+	assert.Equal(t, time.Minute, result.Reset)
+
+	// Other clients have their own bucket
+	assert.True(t, limiter.Allow("other").Allowed)
+}
+
+func TestTokenBucketRefills(t *testing.T) {
+	now := time.Now()
+	limiter := newTestBucket(3, time.Minute, &now)
+
+	for range 3 {
+		limiter.Allow("client")
+	}
+
+	now = now.Add(20 * time.Second)
+	assert.True(t, limiter.Allow("client").Allowed)
+	assert.False(t, limiter.Allow("client").Allowed)
+}
+
+func TestTokenBucketSweepsFullBuckets(t *testing.T) {
+	now := time.Now()
+	limiter := newTestBucket(3, time.Minute, &now)
+
+	limiter.Allow("client")
+	now = now.Add(2 * time.Minute)
+	limiter.Allow("other")
+
+	assert.Len(t, limiter.buckets, 1)
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", Middleware(NewTokenBucket(1, time.Minute), KeyByClientIP), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "1", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", w.Header().Get("RateLimit-Reset"))
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
@@ -0,0 +1,86 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Expired sessions that are never read again are removed this often
+const sweepInterval = time.Minute
+
+type memoryStore struct {
+	mu        sync.Mutex
+	sessions  map[string]Session
+	lastSweep time.Time
+}
+
+// NewMemoryStore keeps sessions in process memory, they are lost on restart
+// and not shared between replicas.
+func NewMemoryStore() Store {
+	return &memoryStore{sessions: make(map[string]Session), lastSweep: time.Now()}
+}
+
+func (s *memoryStore) Create(_ context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(time.Now())
+	s.sessions[session.ID] = *session
+	return nil
+}
+
+func (s *memoryStore) Get(_ context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	// Remove expired sessions lazily
+	if time.Now().After(session.ExpiresAt) {
+		delete(s.sessions, id)
+		return nil, ErrSessionNotFound
+	}
+
+	return &session, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memoryStore) DeleteByUser(_ context.Context, userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, session := range s.sessions {
+		if session.UserID == userID {
+			delete(s.sessions, id)
+		}
+	}
+	return nil
+}
+
+// Forget the expired sessions, callers must hold the lock
+func (s *memoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for id, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, id)
+		}
+	}
+}
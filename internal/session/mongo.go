@@ -0,0 +1,69 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type mongoStore struct {
+	collection *mongo.Collection
+}
+
+// NewMongoStore keeps sessions in the given collection. A TTL index on
+// expires_at is created so MongoDB removes expired sessions by itself.
+func NewMongoStore(ctx context.Context, collection *mongo.Collection) (Store, error) {
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+	if _, err := collection.Indexes().CreateOne(ctx, index); err != nil {
+		slog.Error("error creating sessions TTL index", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &mongoStore{collection: collection}, nil
+}
+
+func (s *mongoStore) Create(ctx context.Context, session *Session) error {
+	_, err := s.collection.InsertOne(ctx, session)
+	return err
+}
+
+func (s *mongoStore) Get(ctx context.Context, id string) (*Session, error) {
+	var session Session
+
+	// The TTL monitor runs periodically, so expired sessions are filtered out here too
+	filter := bson.M{"_id": id, "expires_at": bson.M{"$gt": time.Now().UTC()}}
+	err := s.collection.FindOne(ctx, filter).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		slog.Error("error finding session", slog.Any("error", err))
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+func (s *mongoStore) Delete(ctx context.Context, id string) error {
+	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *mongoStore) DeleteByUser(ctx context.Context, userID string) error {
+	_, err := s.collection.DeleteMany(ctx, bson.M{"user_id": userID})
+	return err
+}
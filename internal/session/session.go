@@ -0,0 +1,126 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrSessionNotFound is used when the requested session does not exist or has expired.
+var ErrSessionNotFound = errors.New("session not found")
+
+// Name of the cookie holding the session token
+const CookieName = "session_id"
+
+// Session stored on the server side
+type Session struct {
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"user_id"`
+	Username  string    `bson:"username"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// Store persists sessions, implementations must not return expired sessions
+type Store interface {
+	Create(ctx context.Context, s *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	Delete(ctx context.Context, id string) error
+	// DeleteByUser removes every session of the given user
+	DeleteByUser(ctx context.Context, userID string) error
+}
+
+// Manager creates sessions and keeps them in sync with the session cookie
+type Manager struct {
+	store  Store
+	ttl    time.Duration
+	secure bool
+}
+
+// Constructor
+func NewManager(store Store, ttl time.Duration, secure bool) *Manager {
+	slog.Info("session manager initialized", slog.Duration("ttl", ttl), slog.Bool("secure_cookie", secure))
+	return &Manager{store: store, ttl: ttl, secure: secure}
+}
+
+// Start a new session for the given user and send the session cookie
+func (m *Manager) Start(c *gin.Context, userID string, username string) (*Session, error) {
+	token, err := GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// Only a hash of the token is stored, so a leaked store cannot be used to hijack sessions
+	now := time.Now().UTC()
+	s := &Session{
+		ID:        HashToken(token),
+		UserID:    userID,
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.ttl),
+	}
+
+	if err := m.store.Create(c.Request.Context(), s); err != nil {
+		slog.Error("error creating session", slog.String("username", username), slog.Any("error", err))
+		return nil, err
+	}
+
+	m.setCookie(c, token, int(m.ttl.Seconds()))
+	slog.Info("session started", slog.String("username", username))
+	return s, nil
+}
+
+// Load the session referenced by the request cookie
+func (m *Manager) Load(c *gin.Context) (*Session, error) {
+	token, err := c.Cookie(CookieName)
+	if err != nil || token == "" {
+		return nil, ErrSessionNotFound
+	}
+
+	return m.store.Get(c.Request.Context(), HashToken(token))
+}
+
+// Destroy the session referenced by the request cookie and clear the cookie
+func (m *Manager) Destroy(c *gin.Context) error {
+	token, err := c.Cookie(CookieName)
+	m.setCookie(c, "", -1)
+	if err != nil || token == "" {
+		return nil
+	}
+
+	if err := m.store.Delete(c.Request.Context(), HashToken(token)); err != nil && !errors.Is(err, ErrSessionNotFound) {
+		slog.Error("error deleting session", slog.Any("error", err))
+		return err
+	}
+
+	slog.Info("session destroyed")
+	return nil
+}
+
+func (m *Manager) setCookie(c *gin.Context, value string, maxAge int) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(CookieName, value, maxAge, "/", "", m.secure, true)
+}
+
+// GenerateToken returns a random URL-safe token suitable for sessions and refresh tokens
+func GenerateToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// HashToken returns the value stored in place of a token, so a leaked store cannot be used to impersonate users
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
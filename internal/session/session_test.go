@@ -0,0 +1,136 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContext(cookie *http.Cookie) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		c.Request.AddCookie(cookie)
+	}
+	return c, w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == CookieName {
+			return cookie
+		}
+	}
+	t.Fatal("session cookie not set")
+	return nil
+}
+
+func TestManagerStartSetsSecureCookie(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewManager(store, time.Hour, true)
+	c, w := newTestContext(nil)
+
+	s, err := manager.Start(c, "user_id", "fake_user")
+	require.NoError(t, err)
+
+	cookie := sessionCookie(t, w)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+	assert.Equal(t, 3600, cookie.MaxAge)
+
+	// The store only knows the hash of the token sent to the client
+	assert.NotEqual(t, cookie.Value, s.ID)
+	assert.Equal(t, HashToken(cookie.Value), s.ID)
+}
+
+func TestManagerLoadAndDestroy(t *testing.T) {
+	store := NewMemoryStore()
+	manager := NewManager(store, time.Hour, false)
+	c, w := newTestContext(nil)
+
+	_, err := manager.Start(c, "user_id", "fake_user")
+	require.NoError(t, err)
+	cookie := sessionCookie(t, w)
+
+	c, _ = newTestContext(cookie)
+	s, err := manager.Load(c)
+	require.NoError(t, err)
+	assert.Equal(t, "user_id", s.UserID)
+	assert.Equal(t, "fake_user", s.Username)
+
+	c, w = newTestContext(cookie)
+	require.NoError(t, manager.Destroy(c))
+	assert.Equal(t, -1, sessionCookie(t, w).MaxAge)
+
+	c, _ = newTestContext(cookie)
+	_, err = manager.Load(c)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestManagerLoadWithoutCookie(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), time.Hour, false)
+	c, _ := newTestContext(nil)
+
+	_, err := manager.Load(c)
+
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestMemoryStoreExpiredSession(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	err := store.Create(ctx, &Session{ID: "expired", ExpiresAt: time.Now().Add(-time.Minute)})
+	require.NoError(t, err)
+
+	_, err = store.Get(ctx, "expired")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	err = store.Delete(ctx, "expired")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func TestMemoryStoreSweepsExpiredSessions(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+
+	require.NoError(t, store.Create(ctx, &Session{ID: "expired", ExpiresAt: time.Now().Add(-time.Minute)}))
+	require.NoError(t, store.Create(ctx, &Session{ID: "valid", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	// Creating a session after the sweep interval forgets the ones that expired without being read
+	store.(*memoryStore).lastSweep = time.Now().Add(-sweepInterval)
+	require.NoError(t, store.Create(ctx, &Session{ID: "new", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	sessions := store.(*memoryStore).sessions
+	assert.NotContains(t, sessions, "expired")
+	assert.Contains(t, sessions, "valid")
+	assert.Contains(t, sessions, "new")
+}
+
+func TestMemoryStoreDeleteByUser(t *testing.T) {
+	store := NewMemoryStore()
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour)
+
+	require.NoError(t, store.Create(ctx, &Session{ID: "a1", UserID: "alice", ExpiresAt: expiresAt}))
+	require.NoError(t, store.Create(ctx, &Session{ID: "a2", UserID: "alice", ExpiresAt: expiresAt}))
+	require.NoError(t, store.Create(ctx, &Session{ID: "b1", UserID: "bob", ExpiresAt: expiresAt}))
+
+	require.NoError(t, store.DeleteByUser(ctx, "alice"))
+
+	_, err := store.Get(ctx, "a1")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = store.Get(ctx, "a2")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = store.Get(ctx, "b1")
+	assert.NoError(t, err)
+}
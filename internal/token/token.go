@@ -0,0 +1,180 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is used when an access or refresh token cannot be verified.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrDisabled is used when no signing keys are configured.
+var ErrDisabled = errors.New("token issuance is disabled")
+
+// Issuer of the signed tokens
+const issuerName = "news_service"
+
+// Claims carried by the access tokens
+type Claims struct {
+	Username string   `json:"username"`
+	Roles    []string `json:"roles,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Pair of tokens returned to API clients
+type Pair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// Issuer signs JWT access tokens and manages the rotating refresh tokens
+type Issuer struct {
+	keys         map[string][]byte
+	activeKeyID  string
+	accessTTL    time.Duration
+	refreshTTL   time.Duration
+	refreshStore session.Store
+}
+
+// Constructor. New tokens are signed with the activeKeyID key, every key in
+// keys is accepted when verifying so keys can be rotated without logging out
+// clients. Refresh tokens are kept (hashed) in refreshStore.
+func NewIssuer(keys map[string]string, activeKeyID string, accessTTL time.Duration, refreshTTL time.Duration, refreshStore session.Store) *Issuer {
+	secrets := make(map[string][]byte, len(keys))
+	for kid, secret := range keys {
+		secrets[kid] = []byte(secret)
+	}
+
+	slog.Info("token issuer initialized", slog.Int("keys", len(secrets)), slog.String("active_kid", activeKeyID))
+	return &Issuer{
+		keys:         secrets,
+		activeKeyID:  activeKeyID,
+		accessTTL:    accessTTL,
+		refreshTTL:   refreshTTL,
+		refreshStore: refreshStore,
+	}
+}
+
+// Enabled reports whether signing keys are configured
+func (i *Issuer) Enabled() bool {
+	return i != nil && i.activeKeyID != ""
+}
+
+// Issue a new access token and refresh token for the given user. Roles are
+// embedded in the access token, so role changes apply once it is refreshed.
+func (i *Issuer) Issue(ctx context.Context, userID string, username string, roles []string) (*Pair, error) {
+	if !i.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	// Sign the access token with the active key and announce it with the kid header
+	now := time.Now().UTC()
+	claims := Claims{
+		Username: username,
+		Roles:    roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuerName,
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(i.accessTTL)),
+		},
+	}
+	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	accessToken.Header["kid"] = i.activeKeyID
+
+	signed, err := accessToken.SignedString(i.keys[i.activeKeyID])
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh tokens are opaque and only their hash is stored
+	refreshToken, err := session.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	err = i.refreshStore.Create(ctx, &session.Session{
+		ID:        session.HashToken(refreshToken),
+		UserID:    userID,
+		Username:  username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(i.refreshTTL),
+	})
+	if err != nil {
+		slog.Error("error storing refresh token", slog.String("username", username), slog.Any("error", err))
+		return nil, err
+	}
+
+	slog.Info("tokens issued", slog.String("username", username), slog.String("kid", i.activeKeyID))
+	return &Pair{
+		AccessToken:  signed,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(i.accessTTL.Seconds()),
+	}, nil
+}
+
+// Verify an access token and return its claims
+func (i *Issuer) Verify(accessToken string) (*Claims, error) {
+	if !i.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(accessToken, claims, i.keyFunc,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuerName),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	return claims, nil
+}
+
+// Rotate consumes a refresh token, which cannot be used again, and returns the
+// session it belonged to so a new pair can be issued for that user.
+func (i *Issuer) Rotate(ctx context.Context, refreshToken string) (*session.Session, error) {
+	if !i.Enabled() {
+		return nil, ErrDisabled
+	}
+
+	id := session.HashToken(refreshToken)
+	s, err := i.refreshStore.Get(ctx, id)
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleting fails if a concurrent request already rotated the same token
+	err = i.refreshStore.Delete(ctx, id)
+	if errors.Is(err, session.ErrSessionNotFound) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Find the verification key announced by the kid header
+func (i *Issuer) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := i.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
@@ -0,0 +1,102 @@
+package token
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	oldSecret = strings.Repeat("o", 32)
+	newSecret = strings.Repeat("n", 32)
+)
+
+func TestIssueAndVerify(t *testing.T) {
+	issuer := NewIssuer(map[string]string{"k1": oldSecret}, "k1", time.Minute, time.Hour, session.NewMemoryStore())
+
+	pair, err := issuer.Issue(context.Background(), "user_id", "fake_user", []string{"editor"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer", pair.TokenType)
+	assert.Equal(t, 60, pair.ExpiresIn)
+	assert.NotEmpty(t, pair.RefreshToken)
+
+	claims, err := issuer.Verify(pair.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user_id", claims.Subject)
+	assert.Equal(t, "fake_user", claims.Username)
+	assert.Equal(t, []string{"editor"}, claims.Roles)
+}
+
+func TestVerifyAfterKeyRotation(t *testing.T) {
+	store := session.NewMemoryStore()
+	before := NewIssuer(map[string]string{"k1": oldSecret}, "k1", time.Minute, time.Hour, store)
+	pair, err := before.Issue(context.Background(), "user_id", "fake_user", []string{"editor"})
+	require.NoError(t, err)
+
+	// Tokens signed with the previous key are still valid while it is listed
+	after := NewIssuer(map[string]string{"k1": oldSecret, "k2": newSecret}, "k2", time.Minute, time.Hour, store)
+	_, err = after.Verify(pair.AccessToken)
+	assert.NoError(t, err)
+
+	// Once the old key is removed they are rejected
+	removed := NewIssuer(map[string]string{"k2": newSecret}, "k2", time.Minute, time.Hour, store)
+	_, err = removed.Verify(pair.AccessToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	issuer := NewIssuer(map[string]string{"k1": oldSecret}, "k1", time.Minute, time.Hour, session.NewMemoryStore())
+
+	expired := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuerName,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+	}})
+	expired.Header["kid"] = "k1"
+	expiredToken, err := expired.SignedString([]byte(oldSecret))
+	require.NoError(t, err)
+
+	unsigned := jwt.NewWithClaims(jwt.SigningMethodNone, Claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuerName,
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+	}})
+	unsigned.Header["kid"] = "k1"
+	unsignedToken, err := unsigned.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	for _, tokenString := range []string{"garbage", expiredToken, unsignedToken} {
+		_, err := issuer.Verify(tokenString)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+}
+
+func TestRotateConsumesRefreshToken(t *testing.T) {
+	issuer := NewIssuer(map[string]string{"k1": oldSecret}, "k1", time.Minute, time.Hour, session.NewMemoryStore())
+
+	pair, err := issuer.Issue(context.Background(), "user_id", "fake_user", []string{"editor"})
+	require.NoError(t, err)
+
+	s, err := issuer.Rotate(context.Background(), pair.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "user_id", s.UserID)
+
+	_, err = issuer.Rotate(context.Background(), pair.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestDisabledIssuer(t *testing.T) {
+	issuer := NewIssuer(map[string]string{}, "", time.Minute, time.Hour, session.NewMemoryStore())
+
+	assert.False(t, issuer.Enabled())
+
+	_, err := issuer.Issue(context.Background(), "user_id", "fake_user", []string{"editor"})
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	_, err = issuer.Verify("token")
+	assert.ErrorIs(t, err, ErrDisabled)
+}
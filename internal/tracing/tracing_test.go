@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func setupRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if attr.Key == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestMiddlewareContinuesTraceparent(t *testing.T) {
+	recorder := setupRecorder(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+
+	var handlerSpan trace.SpanContext
+	router.GET("/news/:id", func(c *gin.Context) {
+		_, span := StartSQL(c.Request.Context(), "SELECT", "news", "SELECT 1;")
+		handlerSpan = span.SpanContext()
+		span.End()
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/news/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a1c9e0f3e5cbdd6d-00f067aa0ba902b7-01")
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	sqlSpan, serverSpan := spans[0], spans[1]
+
+	assert.Equal(t, "GET /news/:id", serverSpan.Name())
+	assert.Equal(t, trace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, "4bf92f3577b34da6a1c9e0f3e5cbdd6d", serverSpan.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", serverSpan.Parent().SpanID().String())
+	assert.Equal(t, int64(200), attributeValue(serverSpan, "http.response.status_code").AsInt64())
+
+	assert.Equal(t, "SELECT news", sqlSpan.Name())
+	assert.Equal(t, serverSpan.SpanContext().SpanID(), sqlSpan.Parent().SpanID())
+	assert.Equal(t, handlerSpan.TraceID(), serverSpan.SpanContext().TraceID())
+	assert.Equal(t, "SELECT 1;", attributeValue(sqlSpan, "db.query.text").AsString())
+}
+
+func TestMiddlewareMarksServerErrors(t *testing.T) {
+	recorder := setupRecorder(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Middleware())
+	router.GET("/boom", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/boom", nil)
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+func TestRecordError(t *testing.T) {
+	recorder := setupRecorder(t)
+
+	_, span := StartMongo(context.Background(), "find", "users")
+	RecordError(span, errors.New("connection reset"))
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "find users", spans[0].Name())
+	assert.Equal(t, "mongodb", attributeValue(spans[0], "db.system.name").AsString())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "connection reset", spans[0].Status().Description)
+}
+
+func TestInitDisabled(t *testing.T) {
+	shutdown, err := Init(context.Background(), Options{Exporter: "none"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+}
+
+func TestInitUnknownExporter(t *testing.T) {
+	_, err := Init(context.Background(), Options{Exporter: "jaeger"})
+
+	assert.Error(t, err)
+}
@@ -1,18 +1,26 @@
 package user
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
 
+	"github.com/ManuelJNunez/news_service/internal/lockout"
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/token"
 	"github.com/gin-gonic/gin"
 )
 
 type Handler struct {
-	svc Service
+	svc      Service
+	sessions *session.Manager
+	tokens   *token.Issuer
+	guard    *lockout.Guard
 }
 
-func NewHandler(svc Service) *Handler {
-	return &Handler{svc: svc}
+func NewHandler(svc Service, sessions *session.Manager, tokens *token.Issuer, guard *lockout.Guard) *Handler {
+	return &Handler{svc: svc, sessions: sessions, tokens: tokens, guard: guard}
 }
 
 func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
@@ -20,38 +28,197 @@ func RegisterRoutes(rg *gin.RouterGroup, h *Handler) {
 
 	grp.GET("/login", h.LoginGet)
 	grp.POST("/login", h.LoginPost)
+	grp.POST("/logout", h.Logout)
+	grp.POST("/token/refresh", h.RefreshToken)
 	grp.POST("/user/register", h.Register)
 	slog.Info("user routes registered")
 }
 
+// RegisterAdminRoutes registers the user administration routes. The group
+// must be restricted to admins, e.g. with RequireAuth and RequireRole(RoleAdmin).
+func RegisterAdminRoutes(rg *gin.RouterGroup, h *Handler) {
+	grp := rg.Group("/users")
+
+	grp.PUT("/:id/roles", h.SetRoles)
+	slog.Info("user admin routes registered")
+}
+
 func (h *Handler) LoginGet(c *gin.Context) {
 	c.HTML(http.StatusOK, "login.html", nil)
 }
 
 func (h *Handler) LoginPost(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
 	// Get credentials from request body
-	var input LoginInput
+	var input LoginRequest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
 
+	if input.IssueTokens && !h.tokens.Enabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token issuance is disabled"})
+		return
+	}
+
+	// Reject the attempt if the username or the client IP are locked
+	clientIP := c.ClientIP()
+	retryAfter, err := h.guard.Check(c.Request.Context(), input.Username, clientIP)
+	if err != nil {
+		logger.Error("login guard error", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login temporarily unavailable"})
+		return
+	}
+	if retryAfter > 0 {
+		loginsTotal.WithLabelValues(loginLocked).Inc()
+		setRetryAfter(c, retryAfter)
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+		return
+	}
+
 	// Find user with provided credentials
-	user, err := h.svc.FindOne(c.Request.Context(), input)
+	user, err := h.svc.FindOne(c.Request.Context(), input.LoginInput)
+	if errors.Is(err, ErrInvalidCredentials) {
+		loginsTotal.WithLabelValues(loginFailed).Inc()
+		if err := h.guard.Failure(c.Request.Context(), input.Username, clientIP); err != nil {
+			logger.Error("login guard error", slog.Any("error", err))
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	if err := h.guard.Success(c.Request.Context(), input.Username); err != nil {
+		logger.Error("login guard error", slog.Any("error", err))
+	}
+	loginsTotal.WithLabelValues(loginSucceeded).Inc()
+
+	// API clients get a token pair instead of a session cookie
+	if input.IssueTokens {
+		pair, err := h.tokens.Issue(c.Request.Context(), user.ID, user.Username, user.Roles)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+			return
+		}
+
+		logger.Info("login successful", slog.String("username", user.Username), slog.Bool("tokens", true))
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Login successful",
+			"username": user.Username,
+			"tokens":   pair,
+		})
+		return
+	}
+
+	// Start a session so the following requests are authenticated
+	if _, err := h.sessions.Start(c, user.ID, user.Username); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+
 	// Send successful response
-	slog.Info("login successful", slog.String("username", user.Username))
+	logger.Info("login successful", slog.String("username", user.Username))
 	c.JSON(http.StatusOK, gin.H{
 		"message":  "Login successful",
 		"username": user.Username,
 	})
 }
 
+func (h *Handler) Logout(c *gin.Context) {
+	// Remove the session from the store and clear the cookie
+	if err := h.sessions.Destroy(c); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+}
+
+func (h *Handler) RefreshToken(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	// Get refresh token from request body
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil || input.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// Consume the refresh token, it cannot be used again
+	s, err := h.tokens.Rotate(c.Request.Context(), input.RefreshToken)
+	if errors.Is(err, token.ErrDisabled) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Token issuance is disabled"})
+		return
+	}
+	if errors.Is(err, token.ErrInvalidToken) {
+		logger.Warn("invalid refresh token", slog.String("client_ip", c.ClientIP()))
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+	if err != nil {
+		logger.Error("refresh token error", slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		return
+	}
+
+	// Check the user still exists before issuing new tokens
+	user, err := h.svc.FindByID(c.Request.Context(), s.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	pair, err := h.tokens.Issue(c.Request.Context(), user.ID, user.Username, user.Roles)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh tokens"})
+		return
+	}
+
+	logger.Info("tokens refreshed", slog.String("username", user.Username))
+	c.JSON(http.StatusOK, pair)
+}
+
+func (h *Handler) SetRoles(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
+	id := c.Param("id")
+
+	// Get roles from request body
+	var input RolesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, err := h.svc.SetRoles(c.Request.Context(), id, input.Roles)
+	if errors.Is(err, ErrInvalidRole) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		logger.Error("set roles error", slog.String("id", id), slog.Any("error", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set roles"})
+		return
+	}
+
+	logger.Info("user roles updated", slog.String("id", id), slog.Any("roles", user.Roles), slog.String("admin", currentUsername(c)))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Roles updated successfully",
+		"user":    user,
+	})
+}
+
 func (h *Handler) Register(c *gin.Context) {
+	logger := logging.FromContext(c.Request.Context())
+
 	// Get credentials from request body
 	var input LoginInput
 	if err := c.ShouldBindJSON(&input); err != nil {
@@ -59,7 +226,7 @@ func (h *Handler) Register(c *gin.Context) {
 		return
 	}
 
-	slog.Debug("register attempt", slog.String("username", input.Username))
+	logger.Debug("register attempt", slog.String("username", input.Username))
 
 	// Check username and password are not empty
 	if input.Username == "" || input.Password == "" {
@@ -71,17 +238,18 @@ func (h *Handler) Register(c *gin.Context) {
 	user, err := h.svc.Create(c.Request.Context(), input)
 	if err != nil {
 		if err == ErrUserAlreadyExists {
-			slog.Warn("user already exists", slog.String("username", input.Username))
+			logger.Warn("user already exists", slog.String("username", input.Username))
 			c.JSON(http.StatusConflict, gin.H{"error": "User already exists"})
 			return
 		}
-		slog.Error("register error", slog.String("username", input.Username), slog.Any("error", err))
+		logger.Error("register error", slog.String("username", input.Username), slog.Any("error", err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create user"})
 		return
 	}
 
 	// Send successful response
-	slog.Info("user registered successfully", slog.String("username", user.Username))
+	registrationsTotal.Inc()
+	logger.Info("user registered successfully", slog.String("username", user.Username))
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered successfully",
 		"user":    user,
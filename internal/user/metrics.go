@@ -0,0 +1,25 @@
+package user
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Login results
+const (
+	loginSucceeded = "success"
+	loginFailed    = "failure"
+	loginLocked    = "locked"
+)
+
+var (
+	loginsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "user_logins_total",
+		Help: "Number of login attempts by result (success, failure or locked).",
+	}, []string{"result"})
+
+	registrationsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "user_registrations_total",
+		Help: "Number of users registered.",
+	})
+)
@@ -0,0 +1,196 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/lockout"
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/ratelimit"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/token"
+	"github.com/gin-gonic/gin"
+)
+
+// Key used to store the authenticated UserOutput in the Gin context
+const ContextUserKey = "user"
+
+// Key used to store why the bearer token of the request was not accepted
+const contextTokenErrorKey = "token_error"
+
+type contextKey struct{}
+
+// WithUser returns a copy of ctx carrying the authenticated user
+func WithUser(ctx context.Context, user *UserOutput) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// FromContext returns the authenticated user carried by ctx, if any
+func FromContext(ctx context.Context) (*UserOutput, bool) {
+	user, ok := ctx.Value(contextKey{}).(*UserOutput)
+	return user, ok
+}
+
+// LoadUser reads the session cookie and, when it references a valid session,
+// stores the logged-in user in the Gin context and in the request context.
+// Requests without a valid session are let through anonymously.
+func LoadUser(svc Service, sessions *session.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
+
+		s, err := sessions.Load(c)
+		if err != nil {
+			if !errors.Is(err, session.ErrSessionNotFound) {
+				logger.Error("error loading session", slog.Any("error", err))
+			}
+			c.Next()
+			return
+		}
+
+		// Get the user from the repository so deleted users lose access immediately
+		user, err := svc.FindByID(c.Request.Context(), s.UserID)
+		if err != nil {
+			logger.Warn("session references an unknown user", slog.String("user_id", s.UserID))
+			c.Next()
+			return
+		}
+
+		setCurrentUser(c, user)
+		c.Next()
+	}
+}
+
+// BearerAuth validates the JWT access token sent in an "Authorization: Bearer"
+// header and stores its user in the Gin context and in the request context.
+// Requests without a valid bearer token are let through anonymously, so public
+// routes keep working; RequireAuth rejects the invalid ones.
+func BearerAuth(tokens *token.Issuer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
+
+		scheme, accessToken, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") {
+			c.Next()
+			return
+		}
+
+		claims, err := tokens.Verify(strings.TrimSpace(accessToken))
+		if err != nil {
+			logger.Warn("invalid bearer token", slog.String("client_ip", c.ClientIP()), slog.Any("error", err))
+			c.Set(contextTokenErrorKey, err)
+			c.Next()
+			return
+		}
+
+		setCurrentUser(c, &UserOutput{ID: claims.Subject, Username: claims.Username, Roles: effectiveRoles(claims.Roles)})
+		c.Next()
+	}
+}
+
+// RequireAuth only lets through requests sent by logged-in users. Users are
+// taken from the session loaded by LoadUser, from the bearer token validated
+// by BearerAuth or from HTTP Basic credentials in the Authorization header.
+// Basic credentials count as login attempts for the guard, like LoginPost.
+// Requests whose bearer token was not accepted by BearerAuth are rejected.
+func RequireAuth(svc Service, guard *lockout.Guard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
+
+		if _, ok := CurrentUser(c); ok {
+			c.Next()
+			return
+		}
+
+		if _, invalid := c.Get(contextTokenErrorKey); invalid {
+			c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Header("WWW-Authenticate", `Basic realm="news_service"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		// Reject the attempt if the username or the client IP are locked
+		clientIP := c.ClientIP()
+		retryAfter, err := guard.Check(c.Request.Context(), username, clientIP)
+		if err != nil {
+			logger.Error("login guard error", slog.Any("error", err))
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Login temporarily unavailable"})
+			return
+		}
+		if retryAfter > 0 {
+			setRetryAfter(c, retryAfter)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, try again later"})
+			return
+		}
+
+		// Check credentials against the user repository
+		user, err := svc.FindOne(c.Request.Context(), LoginInput{Username: username, Password: password})
+		if err != nil {
+			logger.Warn("authentication failed", slog.String("username", username), slog.String("client_ip", clientIP))
+			if errors.Is(err, ErrInvalidCredentials) {
+				if err := guard.Failure(c.Request.Context(), username, clientIP); err != nil {
+					logger.Error("login guard error", slog.Any("error", err))
+				}
+			}
+			c.Header("WWW-Authenticate", `Basic realm="news_service"`)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+			return
+		}
+
+		if err := guard.Success(c.Request.Context(), username); err != nil {
+			logger.Error("login guard error", slog.Any("error", err))
+		}
+
+		setCurrentUser(c, user)
+		c.Next()
+	}
+}
+
+// CurrentUser returns the user authenticated by LoadUser or RequireAuth, if any
+func CurrentUser(c *gin.Context) (*UserOutput, bool) {
+	value, ok := c.Get(ContextUserKey)
+	if !ok {
+		return nil, false
+	}
+	user, ok := value.(*UserOutput)
+	return user, ok
+}
+
+// RateLimitKey identifies clients by their user when they are logged in and by
+// their IP otherwise, to be used with ratelimit.Middleware
+func RateLimitKey(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		return "user:" + user.ID
+	}
+	return ratelimit.KeyByClientIP(c)
+}
+
+// Username of the current user, empty for anonymous requests
+func currentUsername(c *gin.Context) string {
+	if user, ok := CurrentUser(c); ok {
+		return user.Username
+	}
+	return ""
+}
+
+// Tell the client how many seconds to wait before trying again
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
+
+func setCurrentUser(c *gin.Context, user *UserOutput) {
+	c.Set(ContextUserKey, user)
+	ctx := WithUser(c.Request.Context(), user)
+	c.Request = c.Request.WithContext(logging.With(ctx, slog.String("user", user.Username)))
+}
@@ -0,0 +1,132 @@
+package user
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/lockout"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/token"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Service that only knows how to check the credentials of a single user
+type stubLoginService struct {
+	Service
+	username string
+	password string
+}
+
+func (s *stubLoginService) FindOne(_ context.Context, input LoginInput) (*UserOutput, error) {
+	if input.Username != s.username || input.Password != s.password {
+		return nil, ErrInvalidCredentials
+	}
+	return &UserOutput{ID: "1", Username: s.username, Roles: []string{RoleEditor}}, nil
+}
+
+func setupAuthRouter() *gin.Engine {
+	return setupAuthRouterWithTokens(nil)
+}
+
+// Router with a public /public route and a protected /protected route
+func setupAuthRouterWithTokens(tokens *token.Issuer) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	guard := lockout.NewGuard(lockout.NewMemoryStore(), lockout.Policy{
+		MaxFailuresPerUser: 3,
+		MaxFailuresPerIP:   10,
+		Window:             15 * time.Minute,
+		BaseLockout:        time.Minute,
+		MaxLockout:         5 * time.Minute,
+	})
+	svc := &stubLoginService{username: "alice", password: "s3cret"}
+
+	r := gin.New()
+	r.Use(BearerAuth(tokens))
+	r.GET("/public", func(c *gin.Context) {
+		_, ok := CurrentUser(c)
+		c.JSON(http.StatusOK, gin.H{"authenticated": ok})
+	})
+	r.GET("/protected", RequireAuth(svc, guard), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func basicAuthRequest(router *gin.Engine, username string, password string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+	req.SetBasicAuth(username, password)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequireAuthBasicCredentials(t *testing.T) {
+	router := setupAuthRouter()
+
+	assert.Equal(t, http.StatusOK, basicAuthRequest(router, "alice", "s3cret").Code)
+	assert.Equal(t, http.StatusUnauthorized, basicAuthRequest(router, "alice", "wrong").Code)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, `Basic realm="news_service"`, w.Header().Get("WWW-Authenticate"))
+}
+
+func TestRequireAuthBasicCredentialsAreLockedOut(t *testing.T) {
+	router := setupAuthRouter()
+
+	for range 3 {
+		assert.Equal(t, http.StatusUnauthorized, basicAuthRequest(router, "alice", "wrong").Code)
+	}
+
+	// Once locked, not even the right password is checked
+	w := basicAuthRequest(router, "alice", "s3cret")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "60", w.Header().Get("Retry-After"))
+}
+
+func bearerRequest(router *gin.Engine, path string, accessToken string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBearerAuth(t *testing.T) {
+	issuer := token.NewIssuer(map[string]string{"k1": strings.Repeat("k", 32)}, "k1", time.Minute, time.Hour, session.NewMemoryStore())
+	pair, err := issuer.Issue(context.Background(), "1", "alice", []string{RoleEditor})
+	require.NoError(t, err)
+	router := setupAuthRouterWithTokens(issuer)
+
+	w := bearerRequest(router, "/public", pair.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"authenticated":true}`, w.Body.String())
+	assert.Equal(t, http.StatusOK, bearerRequest(router, "/protected", pair.AccessToken).Code)
+}
+
+func TestBearerAuthInvalidTokenIsAnonymousOnPublicRoutes(t *testing.T) {
+	enabled := token.NewIssuer(map[string]string{"k1": strings.Repeat("k", 32)}, "k1", time.Minute, time.Hour, session.NewMemoryStore())
+	disabled := token.NewIssuer(nil, "", time.Minute, time.Hour, session.NewMemoryStore())
+
+	for name, issuer := range map[string]*token.Issuer{"enabled": enabled, "disabled": disabled} {
+		t.Run(name, func(t *testing.T) {
+			router := setupAuthRouterWithTokens(issuer)
+
+			w := bearerRequest(router, "/public", "not-a-token")
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.JSONEq(t, `{"authenticated":false}`, w.Body.String())
+
+			w = bearerRequest(router, "/protected", "not-a-token")
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+			assert.Equal(t, `Bearer error="invalid_token"`, w.Header().Get("WWW-Authenticate"))
+		})
+	}
+}
@@ -7,6 +7,7 @@ type User struct {
 	ID       bson.ObjectID `bson:"_id,omitempty"`
 	Username string        `bson:"username"`
 	Password string        `bson:"password"`
+	Roles    []string      `bson:"roles,omitempty"`
 }
 
 // Data received from the login form
@@ -15,10 +16,27 @@ type LoginInput struct {
 	Password string `json:"password"`
 }
 
+// Data received on login, API clients can ask for JWT tokens instead of relying on the session cookie
+type LoginRequest struct {
+	LoginInput
+	IssueTokens bool `json:"issue_tokens"`
+}
+
+// Data received when assigning roles to a user
+type RolesInput struct {
+	Roles []string `json:"roles"`
+}
+
+// Data received when refreshing tokens
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 // UserOutput for API responses without confidential data
 type UserOutput struct {
-	ID       string `json:"id"`
-	Username string `json:"username"`
+	ID       string   `json:"id"`
+	Username string   `json:"username"`
+	Roles    []string `json:"roles"`
 }
 
 // Convert User to UserOutput
@@ -26,5 +44,6 @@ func (u *User) ToOutput() UserOutput {
 	return UserOutput{
 		ID:       u.ID.Hex(),
 		Username: u.Username,
+		Roles:    effectiveRoles(u.Roles),
 	}
 }
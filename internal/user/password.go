@@ -0,0 +1,191 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Supported password hashing algorithms
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+// ErrInvalidHash is used when a stored password hash cannot be parsed.
+var ErrInvalidHash = errors.New("invalid password hash")
+
+// Options used to hash new passwords. Stored hashes created with other
+// options are still accepted and get rehashed on the next successful login.
+type PasswordHashOptions struct {
+	Algorithm         string
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+// DefaultPasswordHashOptions follows the OWASP recommendations for argon2id
+func DefaultPasswordHashOptions() PasswordHashOptions {
+	return PasswordHashOptions{
+		Algorithm:         AlgorithmArgon2id,
+		Argon2Memory:      64 * 1024,
+		Argon2Iterations:  3,
+		Argon2Parallelism: 2,
+		BcryptCost:        12,
+	}
+}
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password
+	Hash(password string) (string, error)
+	// Verify checks password against an encoded hash and reports whether the
+	// hash should be replaced because it was created with outdated options
+	Verify(password string, encoded string) (match bool, needsRehash bool, err error)
+}
+
+type passwordHasher struct {
+	opts PasswordHashOptions
+}
+
+// Constructor
+func NewPasswordHasher(opts PasswordHashOptions) (PasswordHasher, error) {
+	switch opts.Algorithm {
+	case AlgorithmArgon2id:
+		if opts.Argon2Memory == 0 || opts.Argon2Iterations == 0 || opts.Argon2Parallelism == 0 {
+			return nil, fmt.Errorf("argon2id memory, iterations and parallelism must be positive")
+		}
+	case AlgorithmBcrypt:
+		if opts.BcryptCost < bcrypt.MinCost || opts.BcryptCost > bcrypt.MaxCost {
+			return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported password hashing algorithm %q", opts.Algorithm)
+	}
+
+	return &passwordHasher{opts: opts}, nil
+}
+
+func (h *passwordHasher) Hash(password string) (string, error) {
+	if h.opts.Algorithm == AlgorithmBcrypt {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		return string(hash), err
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.opts.Argon2Iterations, h.opts.Argon2Memory, h.opts.Argon2Parallelism, argon2KeyLength)
+
+	// PHC string format, the same one used by the reference implementation
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.opts.Argon2Memory, h.opts.Argon2Iterations, h.opts.Argon2Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Prefixes of the supported hash formats, anything else is rejected
+var (
+	argon2idPrefix = "$argon2id$"
+	bcryptPrefixes = []string{"$2a$", "$2b$", "$2y$"}
+)
+
+// IsPasswordHash reports whether encoded is in one of the supported hash formats.
+// Passwords stored before hashing was introduced are plain text and must be
+// hashed with "api user hash-passwords" before their users can log in.
+func IsPasswordHash(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix) || isBcryptHash(encoded)
+}
+
+func isBcryptHash(encoded string) bool {
+	for _, prefix := range bcryptPrefixes {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *passwordHasher) Verify(password string, encoded string) (bool, bool, error) {
+	switch {
+	case strings.HasPrefix(encoded, argon2idPrefix):
+		return h.verifyArgon2id(password, encoded)
+	case isBcryptHash(encoded):
+		return h.verifyBcrypt(password, encoded)
+	default:
+		return false, false, ErrInvalidHash
+	}
+}
+
+func (h *passwordHasher) verifyArgon2id(password string, encoded string) (bool, bool, error) {
+	// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrInvalidHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false, ErrInvalidHash
+	}
+
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, false, ErrInvalidHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrInvalidHash
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := h.opts.Algorithm != AlgorithmArgon2id ||
+		memory != h.opts.Argon2Memory ||
+		iterations != h.opts.Argon2Iterations ||
+		parallelism != h.opts.Argon2Parallelism ||
+		len(key) != argon2KeyLength
+	return true, needsRehash, nil
+}
+
+func (h *passwordHasher) verifyBcrypt(password string, encoded string) (bool, bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+
+	cost, err := bcrypt.Cost([]byte(encoded))
+	if err != nil {
+		return false, false, ErrInvalidHash
+	}
+
+	needsRehash := h.opts.Algorithm != AlgorithmBcrypt || cost != h.opts.BcryptCost
+	return true, needsRehash, nil
+}
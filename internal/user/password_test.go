@@ -0,0 +1,128 @@
+package user
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters so the tests run fast
+func testHashOptions() PasswordHashOptions {
+	return PasswordHashOptions{
+		Algorithm:         AlgorithmArgon2id,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+		BcryptCost:        bcrypt.MinCost,
+	}
+}
+
+func TestArgon2idHashAndVerify(t *testing.T) {
+	hasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("s3cret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	match, needsRehash, err := hasher.Verify("s3cret", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, needsRehash)
+
+	match, _, err = hasher.Verify("wrong", hash)
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestHashesAreSalted(t *testing.T) {
+	hasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+
+	first, err := hasher.Hash("s3cret")
+	require.NoError(t, err)
+	second, err := hasher.Hash("s3cret")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+}
+
+func TestVerifyNeedsRehashWhenOptionsChange(t *testing.T) {
+	oldHasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+	hash, err := oldHasher.Hash("s3cret")
+	require.NoError(t, err)
+
+	opts := testHashOptions()
+	opts.Argon2Iterations = 2
+	newHasher, err := NewPasswordHasher(opts)
+	require.NoError(t, err)
+
+	match, needsRehash, err := newHasher.Verify("s3cret", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+}
+
+func TestBcryptHashAndVerify(t *testing.T) {
+	opts := testHashOptions()
+	opts.Algorithm = AlgorithmBcrypt
+	hasher, err := NewPasswordHasher(opts)
+	require.NoError(t, err)
+
+	hash, err := hasher.Hash("s3cret")
+	require.NoError(t, err)
+
+	match, needsRehash, err := hasher.Verify("s3cret", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, needsRehash)
+
+	// Switching the default algorithm upgrades bcrypt hashes to argon2id
+	argonHasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+
+	match, needsRehash, err = argonHasher.Verify("s3cret", hash)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+}
+
+func TestVerifyRejectsUnknownHashFormats(t *testing.T) {
+	hasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+
+	// Plain text passwords do not match, not even themselves
+	for _, encoded := range []string{"s3cret", "", "$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5", "$1$salt$hash"} {
+		match, _, err := hasher.Verify(encoded, encoded)
+		assert.ErrorIs(t, err, ErrInvalidHash, encoded)
+		assert.False(t, match, encoded)
+	}
+
+	assert.False(t, IsPasswordHash("s3cret"))
+	assert.True(t, IsPasswordHash("$2b$04$abc"))
+}
+
+func TestVerifyMalformedHash(t *testing.T) {
+	hasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+
+	_, _, err = hasher.Verify("s3cret", "$argon2id$v=19$broken")
+	assert.ErrorIs(t, err, ErrInvalidHash)
+}
+
+func TestNewPasswordHasherInvalidOptions(t *testing.T) {
+	opts := testHashOptions()
+	opts.Algorithm = "md5"
+	_, err := NewPasswordHasher(opts)
+	assert.Error(t, err)
+
+	opts = testHashOptions()
+	opts.Algorithm = AlgorithmBcrypt
+	opts.BcryptCost = 100
+	_, err = NewPasswordHasher(opts)
+	assert.Error(t, err)
+}
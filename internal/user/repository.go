@@ -5,8 +5,11 @@ import (
 	"errors"
 	"log/slog"
 
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/tracing"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
 // ErrNotFound is used when it is not possible to find the requested User.
@@ -16,8 +19,14 @@ var ErrUserNotFound = errors.New("user not found")
 var ErrUserAlreadyExists = errors.New("user already exists")
 
 type Repository interface {
-	FindOne(ctx context.Context, input LoginInput) (*UserOutput, error)
-	Create(ctx context.Context, input LoginInput) (*UserOutput, error)
+	FindByUsername(ctx context.Context, username string) (*User, error)
+	FindByID(ctx context.Context, id string) (*UserOutput, error)
+	Create(ctx context.Context, user *User) (*UserOutput, error)
+	UpdatePassword(ctx context.Context, id bson.ObjectID, hash string) error
+	SetRoles(ctx context.Context, id string, roles []string) (*UserOutput, error)
+	List(ctx context.Context) ([]UserOutput, error)
+	Delete(ctx context.Context, username string) error
+	FindUnhashed(ctx context.Context) ([]User, error)
 }
 
 type mongoRepository struct {
@@ -28,59 +37,219 @@ func NewRepository(collection *mongo.Collection) Repository {
 	return &mongoRepository{collection: collection}
 }
 
-func (r *mongoRepository) FindOne(ctx context.Context, input LoginInput) (*UserOutput, error) {
+func (r *mongoRepository) FindByUsername(ctx context.Context, username string) (*User, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "find", r.collection.Name())
+	defer span.End()
+
 	var result User
 
-	// Query MongoDB with provided credentials
-	err := r.collection.FindOne(ctx, input).Decode(&result)
+	// Query MongoDB by username only, the password is verified by the service
+	err := r.collection.FindOne(ctx, bson.M{"username": username}).Decode(&result)
 
 	if errors.Is(err, mongo.ErrNoDocuments) {
 		return nil, ErrUserNotFound
 	}
 
 	if err != nil {
-		slog.Error("error finding user", slog.Any("error", err))
+		logger.Error("error finding user", slog.Any("error", err))
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	slog.Info("user found successfully", slog.String("username", result.Username))
-	userOutput := result.ToOutput()
-	return &userOutput, nil
+	logger.Info("user found successfully", slog.String("username", result.Username))
+	return &result, nil
 }
 
-func (r *mongoRepository) Create(ctx context.Context, input LoginInput) (*UserOutput, error) {
-	slog.Debug("creating user", slog.String("username", input.Username))
+func (r *mongoRepository) Create(ctx context.Context, user *User) (*UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "insert", r.collection.Name())
+	defer span.End()
+
+	logger.Debug("creating user", slog.String("username", user.Username))
 
 	// Check if user already exists
 	var existing User
-	err := r.collection.FindOne(ctx, bson.M{"username": input.Username}).Decode(&existing)
+	err := r.collection.FindOne(ctx, bson.M{"username": user.Username}).Decode(&existing)
 	if err == nil {
-		slog.Warn("user already exists", slog.String("username", input.Username))
+		logger.Warn("user already exists", slog.String("username", user.Username))
 		return nil, ErrUserAlreadyExists
 	}
 	if !errors.Is(err, mongo.ErrNoDocuments) {
-		slog.Error("error checking user existence", slog.String("username", input.Username), slog.Any("error", err))
+		logger.Error("error checking user existence", slog.String("username", user.Username), slog.Any("error", err))
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
-	user := User{
-		Username: input.Username,
-		Password: input.Password,
-	}
-
 	// Insert user in collection
 	result, err := r.collection.InsertOne(ctx, user)
 	if err != nil {
-		slog.Error("error creating user", slog.String("username", input.Username), slog.Any("error", err))
+		logger.Error("error creating user", slog.String("username", user.Username), slog.Any("error", err))
+		tracing.RecordError(span, err)
 		return nil, err
 	}
 
 	// Prepare return value
-	userOutput := &UserOutput{
-		ID:       result.InsertedID.(bson.ObjectID).Hex(),
-		Username: input.Username,
-	}
+	user.ID = result.InsertedID.(bson.ObjectID)
+	output := user.ToOutput()
+	userOutput := &output
 
-	slog.Info("user created successfully", slog.String("username", userOutput.Username), slog.String("id", userOutput.ID))
+	logger.Info("user created successfully", slog.String("username", userOutput.Username), slog.String("id", userOutput.ID))
 	return userOutput, nil
 }
+
+func (r *mongoRepository) UpdatePassword(ctx context.Context, id bson.ObjectID, hash string) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "update", r.collection.Name())
+	defer span.End()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": hash}})
+	if err != nil {
+		logger.Error("error updating password", slog.String("id", id.Hex()), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	logger.Info("password updated successfully", slog.String("id", id.Hex()))
+	return nil
+}
+
+func (r *mongoRepository) FindByID(ctx context.Context, id string) (*UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "find", r.collection.Name())
+	defer span.End()
+
+	// IDs that are not valid ObjectIDs cannot belong to any user
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	var result User
+	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&result)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		logger.Error("error finding user by id", slog.String("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	userOutput := result.ToOutput()
+	return &userOutput, nil
+}
+
+func (r *mongoRepository) SetRoles(ctx context.Context, id string, roles []string) (*UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "findAndModify", r.collection.Name())
+	defer span.End()
+
+	// IDs that are not valid ObjectIDs cannot belong to any user
+	objectID, err := bson.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Replace the roles and get the updated document
+	var result User
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"roles": roles}}, opts).Decode(&result)
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+
+	if err != nil {
+		logger.Error("error setting user roles", slog.String("id", id), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	logger.Info("user roles set successfully", slog.String("id", id), slog.Any("roles", roles))
+	userOutput := result.ToOutput()
+	return &userOutput, nil
+}
+
+func (r *mongoRepository) List(ctx context.Context) ([]UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "find", r.collection.Name())
+	defer span.End()
+
+	// Sort by username so listings are stable
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		logger.Error("error listing users", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	var users []User
+	if err := cursor.All(ctx, &users); err != nil {
+		logger.Error("error decoding users", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	outputs := make([]UserOutput, 0, len(users))
+	for i := range users {
+		outputs = append(outputs, users[i].ToOutput())
+	}
+	return outputs, nil
+}
+
+func (r *mongoRepository) Delete(ctx context.Context, username string) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "delete", r.collection.Name())
+	defer span.End()
+
+	result, err := r.collection.DeleteOne(ctx, bson.M{"username": username})
+	if err != nil {
+		logger.Error("error deleting user", slog.String("username", username), slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	logger.Info("user deleted successfully", slog.String("username", username))
+	return nil
+}
+
+// FindUnhashed returns the users whose password is not in a supported hash
+// format, i.e. the ones stored in plain text before hashing was introduced
+func (r *mongoRepository) FindUnhashed(ctx context.Context) ([]User, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.StartMongo(ctx, "find", r.collection.Name())
+	defer span.End()
+
+	filter := bson.M{"password": bson.M{"$not": bson.Regex{Pattern: `^\$(argon2id|2[aby])\$`}}}
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		logger.Error("error finding unhashed passwords", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+
+	var users []User
+	if err := cursor.All(ctx, &users); err != nil {
+		logger.Error("error decoding users", slog.Any("error", err))
+		tracing.RecordError(span, err)
+		return nil, err
+	}
+	return users, nil
+}
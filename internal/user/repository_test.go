@@ -0,0 +1,86 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/drivertest"
+	"go.mongodb.org/mongo-driver/v2/x/mongo/driver/xoptions"
+)
+
+// Repository backed by a fake deployment answering the given server responses in order
+func setupMockRepository(t *testing.T, responses ...bson.D) Repository {
+	opts := options.Client()
+	require.NoError(t, xoptions.SetInternalClientOptions(opts, "deployment", drivertest.NewMockDeployment(responses...)))
+	client, err := mongo.Connect(opts)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Disconnect(context.Background()) //nolint:errcheck
+	})
+	return NewRepository(client.Database("app").Collection("users"))
+}
+
+func TestRepositoryList(t *testing.T) {
+	alice := User{ID: bson.NewObjectID(), Username: "alice", Password: "hash", Roles: []string{RoleEditor}}
+	bob := User{ID: bson.NewObjectID(), Username: "bob", Password: "hash"}
+	repo := setupMockRepository(t, bson.D{
+		{Key: "ok", Value: 1},
+		{Key: "cursor", Value: bson.D{
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: "app.users"},
+			{Key: "firstBatch", Value: bson.A{alice, bob}},
+		}},
+	})
+
+	users, err := repo.List(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []UserOutput{alice.ToOutput(), bob.ToOutput()}, users)
+}
+
+func TestRepositoryListError(t *testing.T) {
+	repo := setupMockRepository(t, bson.D{
+		{Key: "ok", Value: 0},
+		{Key: "errmsg", Value: "not authorized"},
+		{Key: "code", Value: 13},
+	})
+
+	users, err := repo.List(context.Background())
+
+	assert.ErrorContains(t, err, "not authorized")
+	assert.Nil(t, users)
+}
+
+func TestRepositoryDelete(t *testing.T) {
+	repo := setupMockRepository(t, bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 1}})
+
+	assert.NoError(t, repo.Delete(context.Background(), "alice"))
+}
+
+func TestRepositoryDeleteUnknownUser(t *testing.T) {
+	repo := setupMockRepository(t, bson.D{{Key: "ok", Value: 1}, {Key: "n", Value: 0}})
+
+	assert.ErrorIs(t, repo.Delete(context.Background(), "alice"), ErrUserNotFound)
+}
+
+func TestRepositoryFindUnhashed(t *testing.T) {
+	alice := User{ID: bson.NewObjectID(), Username: "alice", Password: "s3cret"}
+	repo := setupMockRepository(t, bson.D{
+		{Key: "ok", Value: 1},
+		{Key: "cursor", Value: bson.D{
+			{Key: "id", Value: int64(0)},
+			{Key: "ns", Value: "app.users"},
+			{Key: "firstBatch", Value: bson.A{alice}},
+		}},
+	})
+
+	users, err := repo.FindUnhashed(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, []User{alice}, users)
+}
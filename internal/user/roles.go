@@ -0,0 +1,86 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// Roles that can be assigned to users
+const (
+	RoleReader = "reader"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+var knownRoles = []string{RoleReader, RoleEditor, RoleAdmin}
+
+// ErrInvalidRole is used when assigning a role that does not exist.
+var ErrInvalidRole = errors.New("invalid role")
+
+// HasRole reports whether the user has any of the given roles. Admins are
+// allowed everywhere.
+func (u *UserOutput) HasRole(roles ...string) bool {
+	if slices.Contains(u.Roles, RoleAdmin) {
+		return true
+	}
+	for _, role := range roles {
+		if slices.Contains(u.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole only lets through users having any of the given roles. It must
+// run after RequireAuth, requests without a user are rejected with 401.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		logger := logging.FromContext(c.Request.Context())
+
+		user, ok := CurrentUser(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		if !user.HasRole(roles...) {
+			logger.Warn("access denied: missing role", slog.String("username", user.Username), slog.Any("required", roles), slog.String("path", c.FullPath()))
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Users stored before roles were introduced are readers
+func effectiveRoles(roles []string) []string {
+	if len(roles) == 0 {
+		return []string{RoleReader}
+	}
+	return roles
+}
+
+// Check every role exists and remove duplicates
+func normalizeRoles(roles []string) ([]string, error) {
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidRole)
+	}
+
+	normalized := make([]string, 0, len(roles))
+	for _, role := range roles {
+		if !slices.Contains(knownRoles, role) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidRole, role)
+		}
+		if !slices.Contains(normalized, role) {
+			normalized = append(normalized, role)
+		}
+	}
+	return normalized, nil
+}
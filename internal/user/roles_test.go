@@ -0,0 +1,68 @@
+package user
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupRoleRouter(current *UserOutput, roles ...string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/protected", func(c *gin.Context) {
+		if current != nil {
+			setCurrentUser(c, current)
+		}
+		c.Next()
+	}, RequireRole(roles...), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return r
+}
+
+func TestRequireRole(t *testing.T) {
+	cases := []struct {
+		name    string
+		current *UserOutput
+		status  int
+	}{
+		{"anonymous", nil, http.StatusUnauthorized},
+		{"reader", &UserOutput{Username: "reader", Roles: []string{RoleReader}}, http.StatusForbidden},
+		{"editor", &UserOutput{Username: "editor", Roles: []string{RoleReader, RoleEditor}}, http.StatusOK},
+		{"admin", &UserOutput{Username: "admin", Roles: []string{RoleAdmin}}, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		router := setupRoleRouter(tc.current, RoleEditor)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/protected", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, tc.status, w.Code, tc.name)
+	}
+}
+
+func TestNormalizeRoles(t *testing.T) {
+	roles, err := normalizeRoles([]string{RoleEditor, RoleReader, RoleEditor})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{RoleEditor, RoleReader}, roles)
+
+	_, err = normalizeRoles([]string{"superuser"})
+	assert.ErrorIs(t, err, ErrInvalidRole)
+
+	_, err = normalizeRoles(nil)
+	assert.ErrorIs(t, err, ErrInvalidRole)
+}
+
+func TestUsersWithoutRolesAreReaders(t *testing.T) {
+	user := User{Username: "legacy"}
+
+	output := user.ToOutput()
+
+	assert.Equal(t, []string{RoleReader}, output.Roles)
+	assert.False(t, output.HasRole(RoleEditor))
+}
@@ -2,43 +2,283 @@ package user
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+
+	"github.com/ManuelJNunez/news_service/internal/logging"
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/ManuelJNunez/news_service/internal/tracing"
 )
 
+// ErrInvalidCredentials is used when the username or the password do not match.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
 type Service interface {
 	FindOne(ctx context.Context, input LoginInput) (*UserOutput, error)
 	Create(ctx context.Context, input LoginInput) (*UserOutput, error)
+	FindByID(ctx context.Context, id string) (*UserOutput, error)
+	SetRoles(ctx context.Context, id string, roles []string) (*UserOutput, error)
+	List(ctx context.Context) ([]UserOutput, error)
+	Delete(ctx context.Context, username string) error
+	SetPassword(ctx context.Context, username string, password string) error
+	HashPlainTextPasswords(ctx context.Context) (int, error)
 }
 
 type service struct {
-	repo Repository
+	repo      Repository
+	hasher    PasswordHasher
+	dummyHash string
+	sessions  []session.Store
 }
 
-// Constructor
-func NewService(repo Repository) Service {
-	slog.Info("news service initialized")
-	return &service{repo: repo}
+// Constructor, the sessions and refresh tokens a user has in the given stores
+// are revoked when the user is deleted or their password is changed
+func NewService(repo Repository, hasher PasswordHasher, sessions ...session.Store) (Service, error) {
+	// Verifying against a dummy hash when the user does not exist makes both
+	// failure cases take the same time, so usernames cannot be enumerated
+	dummyHash, err := hasher.Hash("dummy-password")
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute dummy password hash: %w", err)
+	}
+
+	slog.Info("user service initialized")
+	return &service{repo: repo, hasher: hasher, dummyHash: dummyHash, sessions: sessions}, nil
 }
 
 func (s *service) FindOne(ctx context.Context, input LoginInput) (*UserOutput, error) {
-	user, err := s.repo.FindOne(ctx, input)
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.FindOne")
+	defer span.End()
+
+	user, err := s.repo.FindByUsername(ctx, input.Username)
+	if errors.Is(err, ErrUserNotFound) {
+		_, _, _ = s.hasher.Verify(input.Password, s.dummyHash)
+		logger.Warn("service: invalid credentials", slog.String("username", input.Username))
+		return nil, ErrInvalidCredentials
+	}
 	if err != nil {
-		slog.Error("service: failed to fetch user", slog.Any("error", err))
+		logger.Error("service: failed to fetch user", slog.Any("error", err))
 		return nil, err
 	}
-	slog.Info("service: user fetched successfully")
-	return user, nil
+
+	// Compare the password with the stored hash in constant time
+	match, needsRehash, err := s.hasher.Verify(input.Password, user.Password)
+	if errors.Is(err, ErrInvalidHash) {
+		logger.Error("service: stored password is not a valid hash, run \"api user hash-passwords\" for plain text ones", slog.String("username", input.Username))
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		logger.Error("service: failed to verify password", slog.String("username", input.Username), slog.Any("error", err))
+		return nil, err
+	}
+	if !match {
+		logger.Warn("service: invalid credentials", slog.String("username", input.Username))
+		return nil, ErrInvalidCredentials
+	}
+
+	// Upgrade hashes created with outdated options, login does not fail if this does
+	if needsRehash {
+		s.rehash(ctx, user, input.Password)
+	}
+
+	logger.Info("service: user fetched successfully")
+	output := user.ToOutput()
+	return &output, nil
 }
 
 func (s *service) Create(ctx context.Context, input LoginInput) (*UserOutput, error) {
-	slog.Debug("service: creating user", slog.String("username", input.Username))
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.Create")
+	defer span.End()
+
+	logger.Debug("service: creating user", slog.String("username", input.Username))
+
+	hash, err := s.hasher.Hash(input.Password)
+	if err != nil {
+		logger.Error("service: failed to hash password", slog.String("username", input.Username), slog.Any("error", err))
+		return nil, err
+	}
 
 	// Register user on DB
-	user, err := s.repo.Create(ctx, input)
+	user, err := s.repo.Create(ctx, &User{Username: input.Username, Password: hash, Roles: []string{RoleReader}})
 	if err != nil {
-		slog.Error("service: failed to create user", slog.String("username", input.Username), slog.Any("error", err))
+		logger.Error("service: failed to create user", slog.String("username", input.Username), slog.Any("error", err))
 		return nil, err
 	}
-	slog.Info("service: user created successfully", slog.String("username", input.Username))
+	logger.Info("service: user created successfully", slog.String("username", input.Username))
 	return user, nil
 }
+
+func (s *service) FindByID(ctx context.Context, id string) (*UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.FindByID")
+	defer span.End()
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		logger.Warn("service: failed to fetch user by id", slog.String("id", id), slog.Any("error", err))
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *service) SetRoles(ctx context.Context, id string, roles []string) (*UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.SetRoles")
+	defer span.End()
+
+	logger.Debug("service: setting user roles", slog.String("id", id), slog.Any("roles", roles))
+
+	roles, err := normalizeRoles(roles)
+	if err != nil {
+		logger.Warn("service: invalid roles", slog.String("id", id), slog.Any("error", err))
+		return nil, err
+	}
+
+	user, err := s.repo.SetRoles(ctx, id, roles)
+	if err != nil {
+		logger.Error("service: failed to set user roles", slog.String("id", id), slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: user roles set successfully", slog.String("id", id))
+	return user, nil
+}
+
+func (s *service) List(ctx context.Context) ([]UserOutput, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.List")
+	defer span.End()
+
+	users, err := s.repo.List(ctx)
+	if err != nil {
+		logger.Error("service: failed to list users", slog.Any("error", err))
+		return nil, err
+	}
+	logger.Info("service: users listed successfully", slog.Int("count", len(users)))
+	return users, nil
+}
+
+func (s *service) Delete(ctx context.Context, username string) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.Delete")
+	defer span.End()
+
+	logger.Debug("service: deleting user", slog.String("username", username))
+
+	user, err := s.repo.FindByUsername(ctx, username)
+	if err != nil {
+		logger.Error("service: failed to fetch user", slog.String("username", username), slog.Any("error", err))
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, username); err != nil {
+		logger.Error("service: failed to delete user", slog.String("username", username), slog.Any("error", err))
+		return err
+	}
+	if err := s.revokeSessions(ctx, user); err != nil {
+		return err
+	}
+	logger.Info("service: user deleted successfully", slog.String("username", username))
+	return nil
+}
+
+func (s *service) SetPassword(ctx context.Context, username string, password string) error {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.SetPassword")
+	defer span.End()
+
+	user, err := s.repo.FindByUsername(ctx, username)
+	if err != nil {
+		logger.Error("service: failed to fetch user", slog.String("username", username), slog.Any("error", err))
+		return err
+	}
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		logger.Error("service: failed to hash password", slog.String("username", username), slog.Any("error", err))
+		return err
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		logger.Error("service: failed to set password", slog.String("username", username), slog.Any("error", err))
+		return err
+	}
+	if err := s.revokeSessions(ctx, user); err != nil {
+		return err
+	}
+	logger.Info("service: password set successfully", slog.String("username", username))
+	return nil
+}
+
+// HashPlainTextPasswords hashes the passwords stored in plain text before hashing
+// was introduced, their users cannot log in until then. Returns how many were hashed.
+func (s *service) HashPlainTextPasswords(ctx context.Context) (int, error) {
+	logger := logging.FromContext(ctx)
+
+	ctx, span := tracing.Start(ctx, "user.Service.HashPlainTextPasswords")
+	defer span.End()
+
+	users, err := s.repo.FindUnhashed(ctx)
+	if err != nil {
+		logger.Error("service: failed to find plain text passwords", slog.Any("error", err))
+		return 0, err
+	}
+
+	hashed := 0
+	for _, user := range users {
+		if IsPasswordHash(user.Password) {
+			continue
+		}
+
+		hash, err := s.hasher.Hash(user.Password)
+		if err != nil {
+			logger.Error("service: failed to hash password", slog.String("username", user.Username), slog.Any("error", err))
+			return hashed, err
+		}
+		if err := s.repo.UpdatePassword(ctx, user.ID, hash); err != nil {
+			logger.Error("service: failed to store hashed password", slog.String("username", user.Username), slog.Any("error", err))
+			return hashed, err
+		}
+		hashed++
+	}
+
+	logger.Info("service: plain text passwords hashed", slog.Int("count", hashed))
+	return hashed, nil
+}
+
+// Log the user out everywhere, access tokens already issued stay valid until they expire
+func (s *service) revokeSessions(ctx context.Context, user *User) error {
+	logger := logging.FromContext(ctx)
+
+	for _, store := range s.sessions {
+		if err := store.DeleteByUser(ctx, user.ID.Hex()); err != nil {
+			logger.Error("service: failed to revoke sessions", slog.String("username", user.Username), slog.Any("error", err))
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *service) rehash(ctx context.Context, user *User, password string) {
+	logger := logging.FromContext(ctx)
+
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		logger.Error("service: failed to rehash password", slog.String("username", user.Username), slog.Any("error", err))
+		return
+	}
+
+	if err := s.repo.UpdatePassword(ctx, user.ID, hash); err != nil {
+		logger.Error("service: failed to store rehashed password", slog.String("username", user.Username), slog.Any("error", err))
+		return
+	}
+	logger.Info("service: password rehashed", slog.String("username", user.Username))
+}
@@ -0,0 +1,229 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ManuelJNunez/news_service/internal/session"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+// Repository keeping the users in memory by username
+type stubRepository struct {
+	users map[string]*User
+	err   error
+}
+
+func newStubRepository(users ...*User) *stubRepository {
+	repo := &stubRepository{users: make(map[string]*User)}
+	for _, u := range users {
+		repo.users[u.Username] = u
+	}
+	return repo
+}
+
+func (r *stubRepository) FindByUsername(_ context.Context, username string) (*User, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	u, ok := r.users[username]
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+	return u, nil
+}
+
+func (r *stubRepository) FindByID(_ context.Context, id string) (*UserOutput, error) {
+	for _, u := range r.users {
+		if u.ID.Hex() == id {
+			output := u.ToOutput()
+			return &output, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+func (r *stubRepository) Create(_ context.Context, user *User) (*UserOutput, error) {
+	user.ID = bson.NewObjectID()
+	r.users[user.Username] = user
+	output := user.ToOutput()
+	return &output, nil
+}
+
+func (r *stubRepository) UpdatePassword(_ context.Context, id bson.ObjectID, hash string) error {
+	for _, u := range r.users {
+		if u.ID == id {
+			u.Password = hash
+			return nil
+		}
+	}
+	return ErrUserNotFound
+}
+
+func (r *stubRepository) SetRoles(_ context.Context, id string, roles []string) (*UserOutput, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r *stubRepository) List(_ context.Context) ([]UserOutput, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	outputs := make([]UserOutput, 0, len(r.users))
+	for _, u := range r.users {
+		outputs = append(outputs, u.ToOutput())
+	}
+	return outputs, nil
+}
+
+func (r *stubRepository) Delete(_ context.Context, username string) error {
+	if _, ok := r.users[username]; !ok {
+		return ErrUserNotFound
+	}
+	delete(r.users, username)
+	return nil
+}
+
+func (r *stubRepository) FindUnhashed(_ context.Context) ([]User, error) {
+	var users []User
+	for _, u := range r.users {
+		if !IsPasswordHash(u.Password) {
+			users = append(users, *u)
+		}
+	}
+	return users, nil
+}
+
+func newTestService(t *testing.T, repo Repository, sessions ...session.Store) (Service, PasswordHasher) {
+	hasher, err := NewPasswordHasher(testHashOptions())
+	require.NoError(t, err)
+	svc, err := NewService(repo, hasher, sessions...)
+	require.NoError(t, err)
+	return svc, hasher
+}
+
+// Store a session of the given user and return a function telling whether it is still valid
+func storeSession(t *testing.T, store session.Store, id string, userID string) func() bool {
+	ctx := context.Background()
+	require.NoError(t, store.Create(ctx, &session.Session{ID: id, UserID: userID, ExpiresAt: time.Now().Add(time.Hour)}))
+	return func() bool {
+		_, err := store.Get(ctx, id)
+		return err == nil
+	}
+}
+
+func TestServiceList(t *testing.T) {
+	alice := &User{ID: bson.NewObjectID(), Username: "alice", Roles: []string{RoleEditor}}
+	svc, _ := newTestService(t, newStubRepository(alice))
+
+	users, err := svc.List(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []UserOutput{alice.ToOutput()}, users)
+}
+
+func TestServiceListError(t *testing.T) {
+	repo := newStubRepository()
+	repo.err = errors.New("connection refused")
+	svc, _ := newTestService(t, repo)
+
+	users, err := svc.List(context.Background())
+
+	assert.EqualError(t, err, "connection refused")
+	assert.Nil(t, users)
+}
+
+func TestServiceDeleteRevokesSessions(t *testing.T) {
+	alice := &User{ID: bson.NewObjectID(), Username: "alice"}
+	bob := &User{ID: bson.NewObjectID(), Username: "bob"}
+	repo := newStubRepository(alice, bob)
+	sessions, refreshTokens := session.NewMemoryStore(), session.NewMemoryStore()
+	svc, _ := newTestService(t, repo, sessions, refreshTokens)
+
+	aliceSession := storeSession(t, sessions, "s1", alice.ID.Hex())
+	aliceRefreshToken := storeSession(t, refreshTokens, "r1", alice.ID.Hex())
+	bobSession := storeSession(t, sessions, "s2", bob.ID.Hex())
+
+	err := svc.Delete(context.Background(), "alice")
+
+	assert.NoError(t, err)
+	assert.NotContains(t, repo.users, "alice")
+	assert.False(t, aliceSession())
+	assert.False(t, aliceRefreshToken())
+	assert.True(t, bobSession())
+}
+
+func TestServiceDeleteUnknownUser(t *testing.T) {
+	svc, _ := newTestService(t, newStubRepository())
+
+	err := svc.Delete(context.Background(), "alice")
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestServiceSetPasswordRevokesSessions(t *testing.T) {
+	alice := &User{ID: bson.NewObjectID(), Username: "alice", Password: "old-hash"}
+	sessions := session.NewMemoryStore()
+	svc, hasher := newTestService(t, newStubRepository(alice), sessions)
+	aliceSession := storeSession(t, sessions, "s1", alice.ID.Hex())
+
+	err := svc.SetPassword(context.Background(), "alice", "n3w-s3cret")
+
+	require.NoError(t, err)
+	match, _, err := hasher.Verify("n3w-s3cret", alice.Password)
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, aliceSession())
+}
+
+func TestServiceSetPasswordUnknownUser(t *testing.T) {
+	svc, _ := newTestService(t, newStubRepository())
+
+	err := svc.SetPassword(context.Background(), "alice", "n3w-s3cret")
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+// Hasher whose hashes always fail
+type failingHasher struct{}
+
+func (failingHasher) Hash(string) (string, error) {
+	return "", errors.New("out of memory")
+}
+
+func (failingHasher) Verify(string, string) (bool, bool, error) {
+	return false, false, errors.New("out of memory")
+}
+
+func TestNewServiceFailsWithoutDummyHash(t *testing.T) {
+	svc, err := NewService(newStubRepository(), failingHasher{})
+
+	assert.ErrorContains(t, err, "out of memory")
+	assert.Nil(t, svc)
+}
+
+func TestServiceHashPlainTextPasswords(t *testing.T) {
+	alice := &User{ID: bson.NewObjectID(), Username: "alice", Password: "s3cret"}
+	bob := &User{ID: bson.NewObjectID(), Username: "bob", Password: "$2b$04$alreadyhashed"}
+	svc, hasher := newTestService(t, newStubRepository(alice, bob))
+
+	// Plain text passwords are not accepted on login until they are hashed
+	_, err := svc.FindOne(context.Background(), LoginInput{Username: "alice", Password: "s3cret"})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	hashed, err := svc.HashPlainTextPasswords(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, hashed)
+	assert.Equal(t, "$2b$04$alreadyhashed", bob.Password)
+	match, _, err := hasher.Verify("s3cret", alice.Password)
+	assert.NoError(t, err)
+	assert.True(t, match)
+
+	user, err := svc.FindOne(context.Background(), LoginInput{Username: "alice", Password: "s3cret"})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", user.Username)
+}
@@ -0,0 +1,9 @@
+DELETE FROM Users WHERE email IN ('juan@uoc.edu', 'fulano@uoc.edu', 'perengano@uoc.edu');
+
+DELETE FROM News WHERE title IN (
+    'Lorem ipsum',
+    'Wannacry: el ransomware que alertó a todo el mundo',
+    'Go 1.25 Released',
+    'Listado de empresas afectadas por vulnerabilidades SQLi',
+    'Docker Best Practices'
+);
@@ -0,0 +1 @@
+DROP INDEX news_title_unique;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX news_title_unique ON News (title);
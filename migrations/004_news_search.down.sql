@@ -0,0 +1,2 @@
+DROP INDEX news_search_vector_idx;
+ALTER TABLE News DROP COLUMN search_vector;
@@ -0,0 +1,10 @@
+-- Full-text search over title and body. The seed data mixes English and Spanish
+-- articles, so both configurations are indexed. Title matches rank higher.
+ALTER TABLE News ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', title), 'A') ||
+    setweight(to_tsvector('spanish', title), 'A') ||
+    setweight(to_tsvector('english', coalesce(body, '')), 'B') ||
+    setweight(to_tsvector('spanish', coalesce(body, '')), 'B')
+) STORED;
+
+CREATE INDEX news_search_vector_idx ON News USING GIN (search_vector);
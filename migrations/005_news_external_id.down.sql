@@ -0,0 +1,2 @@
+DROP INDEX news_external_id_unique;
+ALTER TABLE News DROP COLUMN external_id;
@@ -0,0 +1,4 @@
+-- Identifier of the article in the system it was imported from, imports upsert by it
+ALTER TABLE News ADD COLUMN external_id TEXT;
+
+CREATE UNIQUE INDEX news_external_id_unique ON News (external_id);
@@ -0,0 +1,5 @@
+DROP TABLE News_Tags;
+DROP TABLE Tags;
+DROP INDEX news_category_idx;
+ALTER TABLE News DROP COLUMN category;
+DROP TABLE Categories;
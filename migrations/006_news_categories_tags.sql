@@ -0,0 +1,33 @@
+-- Sections of the portal, every article belongs to at most one of them
+CREATE TABLE Categories (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+INSERT INTO Categories (slug, name) VALUES
+    ('tech', 'Technology'),
+    ('security', 'Security'),
+    ('science', 'Science'),
+    ('business', 'Business');
+
+ALTER TABLE News ADD COLUMN category TEXT REFERENCES Categories (slug) ON UPDATE CASCADE ON DELETE SET NULL;
+
+CREATE INDEX news_category_idx ON News (category, datetime DESC, id DESC);
+
+-- Categorize the seed articles, nothing changes where they were not inserted
+UPDATE News SET category = 'tech' WHERE title IN ('Go 1.25 Released', 'Docker Best Practices');
+UPDATE News SET category = 'security' WHERE title IN ('Wannacry: el ransomware que alertó a todo el mundo', 'Listado de empresas afectadas por vulnerabilidades SQLi');
+
+-- Free-form tags, created the first time they are assigned
+CREATE TABLE Tags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE News_Tags (
+    news_id BIGINT NOT NULL REFERENCES News (id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES Tags (id) ON DELETE CASCADE,
+    PRIMARY KEY (news_id, tag_id)
+);
+
+CREATE INDEX news_tags_tag_id_idx ON News_Tags (tag_id);
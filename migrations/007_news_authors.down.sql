@@ -0,0 +1,3 @@
+DROP INDEX news_author_idx;
+ALTER TABLE News DROP COLUMN author_name;
+ALTER TABLE News DROP COLUMN author_id;
@@ -0,0 +1,7 @@
+-- Authors are user accounts, which are stored in MongoDB, so author_id holds the
+-- account id without a foreign key. author_name keeps the byline if the account
+-- is deleted. Articles written before this migration have no author.
+ALTER TABLE News ADD COLUMN author_id TEXT;
+ALTER TABLE News ADD COLUMN author_name TEXT;
+
+CREATE INDEX news_author_idx ON News (author_id, datetime DESC, id DESC);
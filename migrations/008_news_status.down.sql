@@ -0,0 +1,3 @@
+DROP INDEX news_scheduled_idx;
+ALTER TABLE News DROP COLUMN publish_at;
+ALTER TABLE News DROP COLUMN status;
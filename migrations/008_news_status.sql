@@ -0,0 +1,10 @@
+-- Publishing workflow, only published articles are served to the public.
+-- Scheduled articles are published by the scheduler once publish_at is reached.
+ALTER TABLE News ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+    CHECK (status IN ('draft', 'scheduled', 'published', 'archived'));
+ALTER TABLE News ADD COLUMN publish_at TIMESTAMPTZ;
+
+-- Articles dated in the future (e.g. the seed data) were not meant to be public yet
+UPDATE News SET status = 'scheduled', publish_at = datetime WHERE datetime > NOW();
+
+CREATE INDEX news_scheduled_idx ON News (publish_at) WHERE status = 'scheduled';
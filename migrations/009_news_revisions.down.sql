@@ -0,0 +1 @@
+DROP TABLE News_Revisions;
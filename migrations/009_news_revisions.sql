@@ -0,0 +1,21 @@
+-- Content of the articles every time they are saved, numbered from 1 for each
+-- article. The editor is the user account that saved it, empty for imports.
+CREATE TABLE News_Revisions (
+    news_id BIGINT NOT NULL REFERENCES News (id) ON DELETE CASCADE,
+    revision INT NOT NULL,
+    title TEXT NOT NULL,
+    body TEXT,
+    category TEXT,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    editor_id TEXT,
+    editor_name TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (news_id, revision)
+);
+
+-- The current content of the existing articles is their first revision
+INSERT INTO News_Revisions (news_id, revision, title, body, category, tags, editor_id, editor_name, created_at)
+SELECT n.id, 1, n.title, n.body, n.category,
+    ARRAY(SELECT t.name FROM News_Tags nt JOIN Tags t ON t.id = nt.tag_id WHERE nt.news_id = n.id ORDER BY t.name),
+    n.author_id, n.author_name, n.datetime
+FROM News n;
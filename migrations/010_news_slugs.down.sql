@@ -0,0 +1,3 @@
+DROP TABLE News_Slugs;
+DROP INDEX news_slug_unique;
+ALTER TABLE News DROP COLUMN slug;
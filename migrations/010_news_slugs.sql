@@ -0,0 +1,49 @@
+-- Articles are served at /news/<slug>. Slugs are generated from the title when
+-- the article is saved, News_Slugs keeps the previous ones to redirect them.
+ALTER TABLE News ADD COLUMN slug TEXT;
+
+CREATE UNIQUE INDEX news_slug_unique ON News (slug);
+
+CREATE TABLE News_Slugs (
+    slug TEXT PRIMARY KEY,
+    news_id BIGINT NOT NULL REFERENCES News (id) ON DELETE CASCADE
+);
+
+-- Slugs of the existing articles, built like the API does: lower case, accents
+-- removed and anything that is not a letter or a digit replaced by dashes, with
+-- the first free number when the slug is taken. Only the accents listed here
+-- are removed; articles whose title has others (e.g. "ǎ") get the slug of the
+-- API on their next save and their old slug redirects to it. The slugs of
+-- /news/list and /news/search are taken by those routes.
+DO $$
+DECLARE
+    article RECORD;
+    base TEXT;
+    candidate TEXT;
+    n INT;
+BEGIN
+    FOR article IN SELECT id, title FROM News ORDER BY id LOOP
+        base := lower(article.title);
+        base := replace(replace(replace(replace(replace(replace(replace(base,
+            'ß', 'ss'), 'æ', 'ae'), 'œ', 'oe'), 'ø', 'o'), 'ł', 'l'), 'đ', 'd'), 'þ', 'th');
+        base := translate(base,
+            'áàâäãåāăąéèêëēĕėęěíìîïĩīĭįóòôöõōŏőúùûüũūŭůűųýÿŷñńňņçćčĉċďğĝģĥķĺľļŕřŗśšşŝťţźžżŵ',
+            'aaaaaaaaaeeeeeeeeeiiiiiiiioooooooouuuuuuuuuuyyynnnncccccdggghklllrrrssssttzzzw');
+        base := btrim(regexp_replace(base, '[^[:alnum:]]+', '-', 'g'), '-');
+
+        -- Slugs are capped at 80 bytes without splitting characters
+        WHILE octet_length(base) > 80 LOOP
+            base := left(base, -1);
+        END LOOP;
+        base := coalesce(nullif(btrim(base, '-'), ''), 'article');
+
+        candidate := base;
+        n := 1;
+        WHILE candidate IN ('list', 'search') OR EXISTS (SELECT 1 FROM News WHERE slug = candidate) LOOP
+            n := n + 1;
+            candidate := base || '-' || n;
+        END LOOP;
+
+        UPDATE News SET slug = candidate WHERE id = article.id;
+    END LOOP;
+END $$;
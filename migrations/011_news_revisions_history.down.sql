@@ -0,0 +1,7 @@
+ALTER TABLE News_Revisions DROP COLUMN status, DROP COLUMN datetime;
+
+-- The history of deleted articles cannot be kept with the foreign key
+DELETE FROM News_Revisions r WHERE NOT EXISTS (SELECT 1 FROM News n WHERE n.id = r.news_id);
+
+ALTER TABLE News_Revisions ADD CONSTRAINT news_revisions_news_id_fkey
+    FOREIGN KEY (news_id) REFERENCES News (id) ON DELETE CASCADE;
@@ -0,0 +1,6 @@
+-- Revisions outlive their article: deleting one records a last revision with
+-- the deleted status instead of erasing its history. The status and date of
+-- the article are recorded too, so publishing a scheduled article is a revision.
+ALTER TABLE News_Revisions DROP CONSTRAINT news_revisions_news_id_fkey;
+
+ALTER TABLE News_Revisions ADD COLUMN status TEXT, ADD COLUMN datetime TIMESTAMPTZ;
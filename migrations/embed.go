@@ -0,0 +1,9 @@
+// Package migrations embeds the SQL migrations of the PostgreSQL schema in the binary
+package migrations
+
+import "embed"
+
+// FS holds the migrations, named <version>_<name>.sql with an optional <version>_<name>.down.sql
+//
+//go:embed *.sql
+var FS embed.FS
@@ -23,7 +23,7 @@ func waitForAPI(t *testing.T) {
 	deadline := time.Now().Add(timeout)
 
 	for time.Now().Before(deadline) {
-		resp, err := client.Get(baseURL + "/health")
+		resp, err := client.Get(baseURL + "/health/ready")
 		if err == nil {
 			resp.Body.Close()
 			if resp.StatusCode == http.StatusOK {
@@ -54,6 +54,18 @@ func TestE2E_GetNews_Success(t *testing.T) {
 	assert.Contains(t, bodyStr, "Lorem ipsum")
 }
 
+func TestE2E_GetNews_RedirectsToSlug(t *testing.T) {
+	waitForAPI(t)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(baseURL + "/news?id=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusMovedPermanently, resp.StatusCode)
+	assert.Equal(t, "/news/wannacry-el-ransomware-que-alerto-a-todo-el-mundo", resp.Header.Get("Location"))
+}
+
 func TestE2E_GetNews_NotFound(t *testing.T) {
 	waitForAPI(t)
 